@@ -0,0 +1,290 @@
+/**
+ * @file anticheat.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief detect flag sharing and other cheating
+ *
+ * Analysis of submission records and captured flags: teams which submit
+ * same flags at the same time, submissions from foreign addresses and
+ * captures of flags of service without connections to it. Jury does not see
+ * traffic of teams, so connections are imported from traffic records of
+ * game network (see ImportConnections), without them last check is skipped.
+ * Result is only suspicion, it must be reviewed by jury.
+ */
+
+package anticheat
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Kinds of suspicion
+const (
+	SharedFlags    = "shared flags"
+	ForeignAddress = "foreign address"
+	NoConnection   = "no connection"
+)
+
+// Suspicion describe suspicious pattern of teams
+type Suspicion struct {
+	Kind    string
+	Teams   []int
+	Count   int
+	Details string
+}
+
+var (
+	shareWindow   = 10 * time.Second
+	shareMinFlags = 5
+	shareMinRatio = 0.5
+)
+
+// SetSharing set thresholds for shared flags detection, teams are suspicious
+// if at least minFlags (and minRatio part of captures of less active team)
+// same flags are submitted within window
+func SetSharing(window time.Duration, minFlags int, minRatio float64) {
+	shareWindow = window
+	shareMinFlags = minFlags
+	shareMinRatio = minRatio
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func sharedFlags(subs []steward.Submission) (suspicions []Suspicion) {
+
+	byFlag := make(map[string][]steward.Submission)
+	captures := make(map[int]int)
+
+	for _, s := range subs {
+		if !s.Accepted || s.TeamID == 0 {
+			continue
+		}
+		byFlag[s.Flag] = append(byFlag[s.Flag], s)
+		captures[s.TeamID]++
+	}
+
+	shared := make(map[[2]int]int)
+
+	for _, group := range byFlag {
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				a, b := group[i], group[j]
+				if a.TeamID == b.TeamID {
+					continue
+				}
+
+				dt := abs(a.Timestamp.Sub(b.Timestamp))
+				if dt > shareWindow {
+					continue
+				}
+
+				if a.TeamID > b.TeamID {
+					a, b = b, a
+				}
+				shared[[2]int{a.TeamID, b.TeamID}]++
+			}
+		}
+	}
+
+	for pair, count := range shared {
+
+		min := captures[pair[0]]
+		if captures[pair[1]] < min {
+			min = captures[pair[1]]
+		}
+
+		ratio := float64(count) / float64(min)
+
+		if count < shareMinFlags || ratio < shareMinRatio {
+			continue
+		}
+
+		suspicions = append(suspicions, Suspicion{Kind: SharedFlags,
+			Teams: []int{pair[0], pair[1]}, Count: count,
+			Details: fmt.Sprintf("%.0f%% of captures within %s",
+				100*ratio, shareWindow)})
+	}
+
+	return
+}
+
+// owner returns id of team with address in subnets, zero if not found,
+// team is found as in receiver (longest prefix)
+func owner(tree *steward.SubnetTree, ip net.IP) int {
+
+	team := tree.Lookup(ip)
+	if team == nil {
+		return 0
+	}
+
+	return team.ID
+}
+
+func foreignAddresses(subs []steward.Submission, teams []steward.Team) (
+	suspicions []Suspicion, err error) {
+
+	tree, err := steward.NewSubnetTree(teams)
+	if err != nil {
+		return
+	}
+
+	type source struct {
+		team int
+		addr string
+	}
+
+	var sources []source
+	counts := make(map[source]int)
+
+	for _, s := range subs {
+		if s.TeamID == 0 {
+			continue
+		}
+
+		src := source{s.TeamID, s.Addr}
+
+		if _, ok := counts[src]; !ok {
+			sources = append(sources, src)
+		}
+		counts[src]++
+	}
+
+	for _, src := range sources {
+
+		ip := net.ParseIP(src.addr)
+		if ip == nil {
+			continue
+		}
+
+		id := owner(tree, ip)
+		if id == src.team {
+			continue
+		}
+
+		sus := Suspicion{Kind: ForeignAddress, Teams: []int{src.team},
+			Count: counts[src]}
+
+		if id == 0 {
+			sus.Details = src.addr + " is outside team subnets"
+		} else {
+			sus.Teams = append(sus.Teams, id)
+			sus.Details = src.addr + " is in subnet of other team"
+		}
+
+		suspicions = append(suspicions, sus)
+	}
+
+	return
+}
+
+// unconnectedCaptures returns teams which capture flags of service without
+// connections to this service of any team, nothing without connections
+func unconnectedCaptures(caps []steward.Capture, conns []steward.Connection,
+	services []steward.Service) (suspicions []Suspicion) {
+
+	if len(conns) == 0 {
+		return
+	}
+
+	type teamService struct {
+		team    int
+		service int
+	}
+
+	connected := make(map[teamService]bool)
+	for _, c := range conns {
+		connected[teamService{c.TeamID, c.ServiceID}] = true
+	}
+
+	var order []teamService
+	counts := make(map[teamService]int)
+
+	for _, c := range caps {
+		ts := teamService{c.TeamID, c.ServiceID}
+		if connected[ts] {
+			continue
+		}
+
+		if _, ok := counts[ts]; !ok {
+			order = append(order, ts)
+		}
+		counts[ts]++
+	}
+
+	names := make(map[int]string)
+	for _, svc := range services {
+		names[svc.ID] = svc.Name
+	}
+
+	for _, ts := range order {
+		suspicions = append(suspicions, Suspicion{Kind: NoConnection,
+			Teams: []int{ts.team}, Count: counts[ts],
+			Details: "flags of " + names[ts.service] +
+				" without connections to it"})
+	}
+
+	return
+}
+
+// Report returns suspicions, most frequent first
+func Report(db *sql.DB) (suspicions []Suspicion, err error) {
+
+	subs, err := steward.GetAllSubmissions(db)
+	if err != nil {
+		return
+	}
+
+	teams, err := steward.GetTeams(db)
+	if err != nil {
+		return
+	}
+
+	suspicions = sharedFlags(subs)
+
+	foreign, err := foreignAddresses(subs, teams)
+	if err != nil {
+		return
+	}
+
+	suspicions = append(suspicions, foreign...)
+
+	caps, err := steward.GetCaptures(db)
+	if err != nil {
+		return
+	}
+
+	conns, err := steward.GetConnections(db)
+	if err != nil {
+		return
+	}
+
+	services, err := steward.GetServices(db)
+	if err != nil {
+		return
+	}
+
+	suspicions = append(suspicions,
+		unconnectedCaptures(caps, conns, services)...)
+
+	sort.Slice(suspicions, func(i, j int) bool {
+		a, b := suspicions[i], suspicions[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return fmt.Sprint(a.Teams) < fmt.Sprint(b.Teams)
+	})
+
+	return
+}
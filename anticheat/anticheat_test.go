@@ -0,0 +1,200 @@
+/**
+ * @file anticheat_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test cheating detection
+ */
+
+package anticheat
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+func TestSharedFlags(*testing.T) {
+
+	SetSharing(10*time.Second, 5, 0.5)
+
+	start := time.Now()
+
+	var subs []steward.Submission
+
+	submit := func(team int, flag string, delay time.Duration) {
+		subs = append(subs, steward.Submission{TeamID: team,
+			Flag: flag, Accepted: true,
+			Timestamp: start.Add(delay)})
+	}
+
+	for i := 0; i < 10; i++ {
+		flag := fmt.Sprintf("flag%d", i)
+		delay := time.Duration(i) * time.Minute
+
+		// Team 2 always submit just after team 1
+		submit(1, flag, delay)
+		submit(2, flag, delay+3*time.Second)
+
+		// Team 3 capture same flags much later
+		submit(3, flag, delay+time.Minute/2)
+	}
+
+	// Rejected flags are not counted
+	for i := 0; i < 10; i++ {
+		subs = append(subs, steward.Submission{TeamID: 3,
+			Flag: "invalid", Timestamp: start})
+	}
+
+	sus := sharedFlags(subs)
+	if len(sus) != 1 || sus[0].Count != 10 ||
+		sus[0].Teams[0] != 1 || sus[0].Teams[1] != 2 {
+		log.Fatalln("Invalid shared flags:", sus)
+	}
+}
+
+func TestForeignAddresses(*testing.T) {
+
+	teams := []steward.Team{
+		{ID: 1, Name: "Foo", Subnet: "10.0.1.0/24"},
+		{ID: 2, Name: "Bar", Subnet: "10.0.2.0/24, fd00:0:0:2::/64"},
+	}
+
+	subs := []steward.Submission{
+		{TeamID: 1, Addr: "10.0.1.5"},
+		{TeamID: 2, Addr: "fd00:0:0:2::5"},
+		{TeamID: 2, Addr: "10.0.1.5"},
+		{TeamID: 2, Addr: "10.0.1.5"},
+		{TeamID: 1, Addr: "192.0.2.1"},
+		{TeamID: 0, Addr: "192.0.2.1"}, // unknown team
+	}
+
+	sus, err := foreignAddresses(subs, teams)
+	if err != nil {
+		log.Fatalln("Check addresses failed:", err)
+	}
+
+	if len(sus) != 2 {
+		log.Fatalln("Invalid foreign addresses:", sus)
+	}
+
+	if sus[0].Count != 2 || len(sus[0].Teams) != 2 || sus[0].Teams[1] != 1 {
+		log.Fatalln("Invalid other team address:", sus[0])
+	}
+
+	if sus[1].Count != 1 || len(sus[1].Teams) != 1 || sus[1].Teams[0] != 1 {
+		log.Fatalln("Invalid outside address:", sus[1])
+	}
+}
+
+func TestParseConnections(*testing.T) {
+
+	teams := []steward.Team{
+		{ID: 1, Subnet: "10.0.1.0/24", Vulnbox: "10.0.1.3"},
+		{ID: 2, Subnet: "10.0.2.0/24", Vulnbox: "10.0.2.3"},
+	}
+
+	services := []steward.Service{{ID: 1, Port: 8080}, {ID: 2, Port: 22}}
+
+	records := `# src dst port
+10.0.1.15 10.0.2.3 8080
+10.0.1.16 10.0.2.3 8080
+10.0.2.7 10.0.1.3 22
+10.0.1.15 10.0.1.3 8080
+10.100.0.1 10.0.2.3 8080
+10.0.1.15 10.0.2.3 4444
+broken line
+`
+
+	conns, skipped, err := parseConnections(strings.NewReader(records),
+		teams, services)
+	if err != nil {
+		log.Fatalln("Parse connections failed:", err)
+	}
+
+	if skipped != 4 || len(conns) != 2 ||
+		conns[0] != (steward.Connection{TeamID: 1, VictimID: 2,
+			ServiceID: 1, Count: 2}) ||
+		conns[1] != (steward.Connection{TeamID: 2, VictimID: 1,
+			ServiceID: 2, Count: 1}) {
+		log.Fatalln("Invalid connections:", conns, skipped)
+	}
+}
+
+func TestNestedSubnets(*testing.T) {
+
+	// Subnets of teams are inside of subnet of first team, so only
+	// longest prefix gives right team (as in receiver)
+	teams := []steward.Team{
+		{ID: 1, Subnet: "10.0.0.0/16", Vulnbox: "10.0.0.3"},
+		{ID: 2, Subnet: "10.0.1.0/24", Vulnbox: "10.0.1.3"},
+		{ID: 3, Subnet: "10.0.2.0/24", Vulnbox: "10.0.2.3"},
+	}
+
+	subs := []steward.Submission{
+		{TeamID: 1, Addr: "10.0.5.1"},
+		{TeamID: 2, Addr: "10.0.1.5"},
+		{TeamID: 3, Addr: "10.0.2.5"},
+		{TeamID: 2, Addr: "10.0.2.5"},
+	}
+
+	sus, err := foreignAddresses(subs, teams)
+	if err != nil {
+		log.Fatalln("Check addresses failed:", err)
+	}
+
+	if len(sus) != 1 || len(sus[0].Teams) != 2 ||
+		sus[0].Teams[0] != 2 || sus[0].Teams[1] != 3 {
+		log.Fatalln("Invalid foreign addresses:", sus)
+	}
+
+	services := []steward.Service{{ID: 1, Port: 8080}}
+
+	records := `10.0.1.15 10.0.2.3 8080
+10.0.5.1 10.0.1.3 8080
+`
+
+	conns, skipped, err := parseConnections(strings.NewReader(records),
+		teams, services)
+	if err != nil {
+		log.Fatalln("Parse connections failed:", err)
+	}
+
+	if skipped != 0 || len(conns) != 2 ||
+		conns[0] != (steward.Connection{TeamID: 2, VictimID: 3,
+			ServiceID: 1, Count: 1}) ||
+		conns[1] != (steward.Connection{TeamID: 1, VictimID: 2,
+			ServiceID: 1, Count: 1}) {
+		log.Fatalln("Invalid connections:", conns, skipped)
+	}
+}
+
+func TestUnconnectedCaptures(*testing.T) {
+
+	services := []steward.Service{{ID: 1, Name: "Foo"}, {ID: 2, Name: "Bar"}}
+
+	caps := []steward.Capture{
+		{TeamID: 1, VictimID: 2, ServiceID: 1},
+		{TeamID: 1, VictimID: 3, ServiceID: 1},
+		{TeamID: 2, VictimID: 1, ServiceID: 2},
+		{TeamID: 2, VictimID: 3, ServiceID: 2},
+	}
+
+	// Without traffic records check is skipped
+	if len(unconnectedCaptures(caps, nil, services)) != 0 {
+		log.Fatalln("Check without connections")
+	}
+
+	conns := []steward.Connection{{TeamID: 1, VictimID: 2, ServiceID: 1,
+		Count: 10}}
+
+	sus := unconnectedCaptures(caps, conns, services)
+	if len(sus) != 1 || sus[0].Teams[0] != 2 || sus[0].Count != 2 ||
+		!strings.Contains(sus[0].Details, "Bar") {
+		log.Fatalln("Invalid unconnected captures:", sus)
+	}
+}
@@ -0,0 +1,136 @@
+/**
+ * @file connection.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief import connections of teams
+ *
+ * Connections are read from traffic records of game network, one per line:
+ * source address, destination address and destination port, separated by
+ * spaces (e.g. exported from conntrack or netflow of router). Source is
+ * resolved to team by subnets, destination to vulnbox and service by port.
+ */
+
+package anticheat
+
+import (
+	"bufio"
+	"database/sql"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+func parseConnections(r io.Reader, teams []steward.Team,
+	services []steward.Service) (conns []steward.Connection, skipped int,
+	err error) {
+
+	tree, err := steward.NewSubnetTree(teams)
+	if err != nil {
+		return
+	}
+
+	vulnboxes := make(map[string]int)
+	for _, team := range teams {
+		ip := net.ParseIP(team.Vulnbox)
+		if ip != nil {
+			vulnboxes[ip.String()] = team.ID
+		}
+	}
+
+	ports := make(map[int]int)
+	for _, svc := range services {
+		ports[svc.Port] = svc.ID
+	}
+
+	var order []steward.Connection
+	counts := make(map[steward.Connection]int)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			skipped++
+			continue
+		}
+
+		src := net.ParseIP(fields[0])
+		dst := net.ParseIP(fields[1])
+		port, e := strconv.Atoi(fields[2])
+		if src == nil || dst == nil || e != nil {
+			skipped++
+			continue
+		}
+
+		team := owner(tree, src)
+		victim, vulnbox := vulnboxes[dst.String()]
+		service, known := ports[port]
+
+		// Connections of jury and to own vulnbox are not interesting
+		if team == 0 || !vulnbox || !known || team == victim {
+			skipped++
+			continue
+		}
+
+		c := steward.Connection{TeamID: team, VictimID: victim,
+			ServiceID: service}
+
+		if _, ok := counts[c]; !ok {
+			order = append(order, c)
+		}
+		counts[c]++
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return
+	}
+
+	for _, c := range order {
+		c.Count = counts[c]
+		conns = append(conns, c)
+	}
+
+	return
+}
+
+// ImportConnections add connections from traffic records to database,
+// returns count of imported and skipped records
+func ImportConnections(db *sql.DB, r io.Reader) (imported, skipped int,
+	err error) {
+
+	teams, err := steward.GetTeams(db)
+	if err != nil {
+		return
+	}
+
+	services, err := steward.GetServices(db)
+	if err != nil {
+		return
+	}
+
+	conns, skipped, err := parseConnections(r, teams, services)
+	if err != nil {
+		return
+	}
+
+	for _, c := range conns {
+		err = steward.AddConnection(db, c)
+		if err != nil {
+			return
+		}
+
+		imported += c.Count
+	}
+
+	return
+}
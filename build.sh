@@ -21,6 +21,7 @@ mkdir -p bin
 
 go build -ldflags "${LDFLAGS}" -o bin/tin_foil_hat github.com/jollheef/tin_foil_hat
 go build -ldflags "${LDFLAGS}" -o bin/tfhctl github.com/jollheef/tin_foil_hat/cli/tfhctl
+go build -ldflags "${LDFLAGS}" -o bin/tfh-agent github.com/jollheef/tin_foil_hat/cli/tfh-agent
 
 END_TIME=`date +%s`
 RUN_TIME=$((END_TIME-START_TIME))
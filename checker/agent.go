@@ -0,0 +1,611 @@
+/**
+ * @file agent.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief checker agent for netboxes
+ *
+ * Provide agent (see tfh-agent), which runs checker executables on netbox,
+ * and checker, which sends jobs to agent of team netbox. Jury keeps one
+ * persistent connection to each agent, jobs and results are json lines
+ * (as in worker protocol) with id, so jobs are run in parallel.
+ *
+ * Connection is mutual TLS, certificates of jury and agents are signed by
+ * one CA, certificates of agents are issued for name tfh-agent. Failure of
+ * agent is reported by ErrAgentUnavailable, not by state of service.
+ *
+ * Jury sends name of service, agent runs checker with same name from own
+ * checkers directory only.
+ */
+
+package checker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// agentServerName is name in certificates of agents, agents are dialed by
+// address of netbox, so name is the same for all of them
+const agentServerName = "tfh-agent"
+
+// ErrAgentUnavailable is failure of agent or of connection to it, it is
+// failure of jury, not of service of team
+var ErrAgentUnavailable = errors.New("agent is unavailable")
+
+var (
+	agentPort = 9100 // used if netbox address has no port
+	agentTLS  *tls.Config
+)
+
+// SetAgent set default port of agents and tls config of jury (see
+// JuryTLSConfig)
+func SetAgent(port int, config *tls.Config) {
+	agentPort = port
+	agentTLS = config
+}
+
+// loadCerts returns pool with CA certificate and own certificate
+func loadCerts(caFile, certFile, keyFile string) (pool *x509.CertPool,
+	cert tls.Certificate, err error) {
+
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return
+	}
+
+	pool = x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		err = errors.New("no certificates in " + caFile)
+		return
+	}
+
+	cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+	return
+}
+
+// JuryTLSConfig returns config of jury connections to agents, certificate
+// of agent must be signed by CA and issued for name tfh-agent
+func JuryTLSConfig(caFile, certFile, keyFile string) (config *tls.Config,
+	err error) {
+
+	pool, cert, err := loadCerts(caFile, certFile, keyFile)
+	if err != nil {
+		return
+	}
+
+	config = &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{cert},
+		ServerName:   agentServerName,
+		MinVersion:   tls.VersionTLS13,
+	}
+
+	return
+}
+
+// AgentTLSConfig returns config of agent, certificate of jury must be
+// signed by CA
+func AgentTLSConfig(caFile, certFile, keyFile string) (config *tls.Config,
+	err error) {
+
+	pool, cert, err := loadCerts(caFile, certFile, keyFile)
+	if err != nil {
+		return
+	}
+
+	config = &tls.Config{
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+	}
+
+	return
+}
+
+// agentJob contains checker job and info about checker executable
+type agentJob struct {
+	job
+	Service      string        `json:"service"` // name of checker
+	JSONProtocol bool          `json:"json_protocol"`
+	Vulns        int           `json:"vulns"`
+	Timeout      time.Duration `json:"timeout"`
+}
+
+// agentReply contains full result of checker run
+type agentReply struct {
+	ID       uint64               `json:"id"`
+	State    steward.ServiceState `json:"state"`
+	Error    string               `json:"error"`
+	Rejected bool                 `json:"rejected"` // job is not run
+	Result   Result               `json:"result"`
+	Command  string               `json:"command"`
+	Stdout   string               `json:"stdout"`
+	Stderr   string               `json:"stderr"`
+	ExitCode int                  `json:"exit_code"`
+}
+
+// lineWriter writes json lines, safe for concurrent use
+type lineWriter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+func (w *lineWriter) writeJSON(v interface{}) (err error) {
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+
+	defer w.mu.Unlock()
+
+	_, err = w.w.Write(append(buf, '\n'))
+	return
+}
+
+// resolveChecker returns path of checker of service in checkers directory,
+// anything outside of directory is rejected
+func resolveChecker(dir, service string) (path string, err error) {
+
+	if service == "" || service == "." || service == ".." ||
+		filepath.Base(service) != service {
+		err = errors.New("invalid checker name '" + service + "'")
+		return
+	}
+
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return
+	}
+
+	path, err = filepath.EvalSymlinks(filepath.Join(root, service))
+	if err != nil {
+		return
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return
+	}
+
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		err = errors.New("checker '" + service + "' is outside of " + dir)
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	if !info.Mode().IsRegular() {
+		err = errors.New("checker '" + service + "' is not a file")
+	}
+
+	return
+}
+
+// runAgentJob run checker executable for job on agent side
+func runAgentJob(j agentJob, checkersDir string) (rep agentReply) {
+
+	rep.ID = j.ID
+
+	path, err := resolveChecker(checkersDir, j.Service)
+	if err != nil {
+		log.Println("Reject job:", err)
+		rep.Error = "agent rejects checker: " + err.Error()
+		rep.Rejected = true
+		rep.State = steward.StatusError
+		return
+	}
+
+	t := j.Timeout
+	if t <= 0 {
+		t = timeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t)
+
+	defer cancel()
+
+	c := ExecChecker{Path: path, JSONProtocol: j.JSONProtocol,
+		Vulns: j.Vulns}
+
+	var res Result
+
+	switch j.Command {
+	case "put":
+		res, rep.State, err = c.Put(ctx, j.Host, j.Port, j.Vuln, j.Arg)
+	case "get":
+		res, rep.State, err = c.Get(ctx, j.Host, j.Port, j.Vuln, j.Arg)
+	case "chk":
+		res, rep.State, err = c.Check(ctx, j.Host, j.Port)
+	default:
+		log.Println("Reject job: unknown command", j.Command)
+		rep.Error = "agent rejects command " + j.Command
+		rep.Rejected = true
+		rep.State = steward.StatusError
+		return
+	}
+
+	if err != nil {
+		rep.Error = err.Error()
+	}
+
+	rep.Result = res
+	rep.Command, rep.Stdout, rep.Stderr, rep.ExitCode = res.Command,
+		res.Stdout, res.Stderr, res.ExitCode
+
+	return
+}
+
+func serveAgentConn(conn *tls.Conn, checkersDir string) {
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	err := conn.Handshake()
+	if err != nil {
+		log.Println("Authentication of", conn.RemoteAddr(),
+			"failed:", err)
+		return
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	r := bufio.NewReader(conn)
+	w := &lineWriter{w: conn}
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			if err != io.EOF {
+				log.Println("Read job failed:", err)
+			}
+			return
+		}
+
+		var j agentJob
+
+		err = json.Unmarshal(line, &j)
+		if err != nil {
+			log.Println("Invalid job:", err)
+			return
+		}
+
+		go func() {
+			err := w.writeJSON(runAgentJob(j, checkersDir))
+			if err != nil {
+				log.Println("Write reply failed:", err)
+			}
+		}()
+	}
+}
+
+// ServeAgent accept tls connections of jury and run checker jobs from
+// them (see AgentTLSConfig), checkers are run only from checkers directory
+func ServeAgent(ln net.Listener, config *tls.Config,
+	checkersDir string) (err error) {
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+
+		go serveAgentConn(tls.Server(conn, config), checkersDir)
+	}
+}
+
+// agentSession is tls connection to agent
+type agentSession struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *lineWriter
+	pending map[uint64]chan agentReply // guarded by agentConn mutex
+}
+
+// agentConn keeps connection to agent, reconnects on failure
+type agentConn struct {
+	addr    string
+	config  *tls.Config
+	mu      sync.Mutex
+	sess    *agentSession
+	dialing chan struct{} // closed after dial, nil if there is no dial
+	id      uint64
+}
+
+var (
+	agents      = make(map[string]*agentConn) // { address : agent }
+	agentsMutex sync.Mutex
+)
+
+func getAgent(addr string, config *tls.Config) (a *agentConn) {
+
+	agentsMutex.Lock()
+
+	defer agentsMutex.Unlock()
+
+	a, ok := agents[addr]
+	if !ok || a.config != config {
+		a = &agentConn{addr: addr, config: config}
+		agents[addr] = a
+	}
+
+	return
+}
+
+// agentAddr returns address of agent on netbox
+func agentAddr(netbox string) string {
+
+	if _, _, err := net.SplitHostPort(netbox); err == nil {
+		return netbox
+	}
+
+	return net.JoinHostPort(netbox, strconv.Itoa(agentPort))
+}
+
+func (a *agentConn) dial(ctx context.Context) (sess *agentSession,
+	err error) {
+
+	if a.config == nil {
+		err = errors.New("tls config of agents is not set")
+		return
+	}
+
+	d := tls.Dialer{Config: a.config}
+
+	conn, err := d.DialContext(ctx, "tcp", a.addr)
+	if err != nil {
+		return
+	}
+
+	sess = &agentSession{conn: conn, r: bufio.NewReader(conn),
+		w: &lineWriter{w: conn}, pending: make(map[uint64]chan agentReply)}
+
+	return
+}
+
+// session returns current session, connects if there is no session. Dial
+// is done without lock, so jobs for other sessions are not blocked by slow
+// agent, and jobs which wait for dial of other job return on their timeout.
+func (a *agentConn) session(ctx context.Context) (sess *agentSession,
+	err error) {
+
+	for {
+		a.mu.Lock()
+
+		if a.sess != nil {
+			sess = a.sess
+			a.mu.Unlock()
+			return
+		}
+
+		dialing := a.dialing
+		if dialing == nil {
+			dialing = make(chan struct{})
+			a.dialing = dialing
+			a.mu.Unlock()
+
+			sess, err = a.dial(ctx)
+
+			a.mu.Lock()
+			if err == nil {
+				a.sess = sess
+			}
+			a.dialing = nil
+			a.mu.Unlock()
+
+			close(dialing)
+
+			if err == nil {
+				go a.read(sess)
+			}
+			return
+		}
+
+		a.mu.Unlock()
+
+		select {
+		case <-dialing: // connected or failed, check again
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// read replies of session, on failure all pending jobs are failed
+func (a *agentConn) read(sess *agentSession) {
+
+	var err error
+
+	for {
+		var line []byte
+
+		line, err = sess.r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+
+		var rep agentReply
+
+		err = json.Unmarshal(line, &rep)
+		if err != nil {
+			break
+		}
+
+		a.mu.Lock()
+		ch, ok := sess.pending[rep.ID]
+		delete(sess.pending, rep.ID)
+		a.mu.Unlock()
+
+		if ok {
+			ch <- rep
+		}
+	}
+
+	log.Println("Connection to agent", a.addr, "lost:", err)
+
+	sess.conn.Close()
+
+	a.mu.Lock()
+
+	defer a.mu.Unlock()
+
+	if a.sess == sess {
+		a.sess = nil
+	}
+
+	for id, ch := range sess.pending {
+		close(ch)
+		delete(sess.pending, id)
+	}
+}
+
+// unavailable returns result of job failed by agent or connection to it
+func (a *agentConn) unavailable(res Result, reason string, e error) (
+	Result, steward.ServiceState, error) {
+
+	if e != nil {
+		reason += ": " + e.Error()
+	}
+
+	res.Private = "agent is unavailable, " + reason
+
+	return res, steward.StatusError,
+		fmt.Errorf("%w: %s: %s", ErrAgentUnavailable, a.addr, reason)
+}
+
+func (a *agentConn) do(ctx context.Context, j agentJob) (res Result,
+	state steward.ServiceState, err error) {
+
+	if deadline, ok := ctx.Deadline(); ok {
+		j.Timeout = time.Until(deadline)
+	}
+
+	res.Command = fmt.Sprintf("%s: %s %s %s %d %s", a.addr, j.Service,
+		j.Command, j.Host, j.Port, j.Arg)
+
+	sess, e := a.session(ctx)
+	if e != nil {
+		return a.unavailable(res, "connect failed", e)
+	}
+
+	ch := make(chan agentReply, 1)
+
+	a.mu.Lock()
+	a.id++
+	j.ID = a.id
+	sess.pending[j.ID] = ch
+	a.mu.Unlock()
+
+	e = sess.w.writeJSON(j)
+	if e != nil {
+		a.mu.Lock()
+		delete(sess.pending, j.ID)
+		a.mu.Unlock()
+
+		// Reader fails pending jobs and drops session
+		sess.conn.Close()
+
+		return a.unavailable(res, "send job failed", e)
+	}
+
+	select {
+	case rep, ok := <-ch:
+		if !ok {
+			return a.unavailable(res, "connection lost", nil)
+		}
+
+		command := res.Command
+		if rep.Command != "" {
+			command = a.addr + ": " + rep.Command
+		}
+
+		res = rep.Result
+		res.Command, res.Stdout, res.Stderr, res.ExitCode = command,
+			rep.Stdout, rep.Stderr, rep.ExitCode
+
+		// Misconfiguration of jury, not failure of service
+		if rep.Rejected {
+			return a.unavailable(res, rep.Error, nil)
+		}
+
+		state = rep.State
+		if rep.Error != "" {
+			err = errors.New(rep.Error)
+		}
+
+	case <-ctx.Done():
+		a.mu.Lock()
+		delete(sess.pending, j.ID)
+		a.mu.Unlock()
+
+		res.Private = "agent does not reply before timeout"
+		res.ExitCode = 124
+		state = steward.StatusDown
+	}
+
+	return
+}
+
+// AgentChecker runs checker executable on netbox by agent
+type AgentChecker struct {
+	Addr         string      // address of agent
+	TLS          *tls.Config // see JuryTLSConfig
+	Service      string      // name of checker in checkers directory of agent
+	JSONProtocol bool
+	Vulns        int
+}
+
+func (c AgentChecker) do(ctx context.Context, j job) (Result,
+	steward.ServiceState, error) {
+
+	return getAgent(c.Addr, c.TLS).do(ctx, agentJob{job: j,
+		Service: c.Service, JSONProtocol: c.JSONProtocol, Vulns: c.Vulns})
+}
+
+// Put flag to service
+func (c AgentChecker) Put(ctx context.Context, addr string, port, vuln int,
+	flag string) (res Result, state steward.ServiceState, err error) {
+
+	return c.do(ctx, job{Command: "put", Host: addr, Port: port,
+		Vuln: vuln, Arg: flag})
+}
+
+// Get flag from service
+func (c AgentChecker) Get(ctx context.Context, addr string, port, vuln int,
+	cred string) (res Result, state steward.ServiceState, err error) {
+
+	return c.do(ctx, job{Command: "get", Host: addr, Port: port,
+		Vuln: vuln, Arg: cred})
+}
+
+// Check service logic
+func (c AgentChecker) Check(ctx context.Context, addr string, port int) (
+	res Result, state steward.ServiceState, err error) {
+
+	return c.do(ctx, job{Command: "chk", Host: addr, Port: port})
+}
@@ -0,0 +1,341 @@
+/**
+ * @file agent_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test checker agent
+ */
+
+package checker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func writePEM(path, kind string, der []byte) {
+
+	err := ioutil.WriteFile(path,
+		pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600)
+	if err != nil {
+		log.Fatalln("Write", path, "failed:", err)
+	}
+}
+
+// issue write certificate and key to dir, self-signed if ca is nil
+func issue(dir, name string, ca *testCA, template *x509.Certificate) (
+	issued testCA) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Fatalln("Generate key failed:", err)
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.Subject = pkix.Name{CommonName: name}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parent, parentKey := template, key
+	if ca != nil {
+		parent, parentKey = ca.cert, ca.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent,
+		&key.PublicKey, parentKey)
+	if err != nil {
+		log.Fatalln("Create certificate failed:", err)
+	}
+
+	issued.cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		log.Fatalln("Parse certificate failed:", err)
+	}
+	issued.key = key
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		log.Fatalln("Marshal key failed:", err)
+	}
+
+	writePEM(filepath.Join(dir, name+".crt"), "CERTIFICATE", der)
+	writePEM(filepath.Join(dir, name+".key"), "EC PRIVATE KEY", keyDER)
+
+	return
+}
+
+// writeCerts write CA (ca.crt), certificates of agent (agent.crt) and jury
+// (jury.crt) signed by it to dir
+func writeCerts(dir string) {
+
+	ca := issue(dir, "ca", nil, &x509.Certificate{IsCA: true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign})
+
+	issue(dir, "agent", &ca, &x509.Certificate{
+		DNSNames:    []string{agentServerName},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+
+	issue(dir, "jury", &ca, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+}
+
+// testCerts returns directory with certificates (see writeCerts)
+func testCerts() (dir string) {
+
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		log.Fatalln("Create certs dir failed:", err)
+	}
+
+	writeCerts(dir)
+
+	return
+}
+
+func juryTLS(dir string) *tls.Config {
+
+	config, err := JuryTLSConfig(filepath.Join(dir, "ca.crt"),
+		filepath.Join(dir, "jury.crt"), filepath.Join(dir, "jury.key"))
+	if err != nil {
+		log.Fatalln("Load jury certificates failed:", err)
+	}
+
+	return config
+}
+
+func startAgent(certs, checkersDir string) (ln net.Listener) {
+
+	config, err := AgentTLSConfig(filepath.Join(certs, "ca.crt"),
+		filepath.Join(certs, "agent.crt"),
+		filepath.Join(certs, "agent.key"))
+	if err != nil {
+		log.Fatalln("Load agent certificates failed:", err)
+	}
+
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalln("Listen failed:", err)
+	}
+
+	go ServeAgent(ln, config, checkersDir)
+
+	return
+}
+
+// checkersDir returns directory with script, named as service
+func checkersDir(service, text string) (dir string) {
+
+	dir, err := ioutil.TempDir("", "checkers")
+	if err != nil {
+		log.Fatalln("Create checkers dir failed:", err)
+	}
+
+	path := writeScript(text)
+
+	err = os.Rename(path, filepath.Join(dir, service))
+	if err != nil {
+		log.Fatalln("Move script failed:", err)
+	}
+
+	return
+}
+
+func TestAgentChecker(*testing.T) {
+
+	dir := checkersDir("FooService", `[ "$1" = "chk" ] && sleep 10
+echo '{"status": "up", "cred": "'$4:$5'"}'`)
+
+	defer os.RemoveAll(dir)
+
+	certs := testCerts()
+
+	defer os.RemoveAll(certs)
+
+	ln := startAgent(certs, dir)
+
+	defer ln.Close()
+
+	c := AgentChecker{Addr: ln.Addr().String(), TLS: juryTLS(certs),
+		Service: "FooService", JSONProtocol: true, Vulns: 2}
+
+	// Jobs share one connection
+	for i := 0; i < 3; i++ {
+		res, state, err := c.Put(context.Background(), "127.0.0.1",
+			80, 2, "FLAG")
+		if err != nil || state != steward.StatusUP ||
+			res.Cred != "FLAG:2" {
+			log.Fatalln("Invalid agent put result:", res, state, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		100*time.Millisecond)
+
+	defer cancel()
+
+	_, state, err := c.Check(ctx, "127.0.0.1", 80)
+	if err != nil || state != steward.StatusDown {
+		log.Fatalln("Invalid agent timeout result:", state, err)
+	}
+}
+
+func TestAgentAuth(*testing.T) {
+
+	certs := testCerts()
+
+	defer os.RemoveAll(certs)
+
+	// Same names, other CA
+	other := testCerts()
+
+	defer os.RemoveAll(other)
+
+	for _, pair := range [][2]string{{certs, other}, {other, certs}} {
+
+		ln := startAgent(pair[0], os.TempDir())
+
+		c := AgentChecker{Addr: ln.Addr().String(),
+			TLS: juryTLS(pair[1]), Service: "true"}
+
+		ctx, cancel := context.WithTimeout(context.Background(),
+			time.Second)
+
+		_, state, err := c.Check(ctx, "127.0.0.1", 80)
+		if !errors.Is(err, ErrAgentUnavailable) ||
+			state != steward.StatusError {
+			log.Fatalln("Agent with other CA is used:", state, err)
+		}
+
+		cancel()
+		ln.Close()
+	}
+}
+
+func TestAgentUnavailable(*testing.T) {
+
+	certs := testCerts()
+
+	defer os.RemoveAll(certs)
+
+	// Accept connections, but do not reply
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalln("Listen failed:", err)
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	c := AgentChecker{Addr: ln.Addr().String(), TLS: juryTLS(certs),
+		Service: "FooService"}
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+
+	// Jobs wait for dial of other job not longer than own timeout
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(),
+				300*time.Millisecond)
+
+			defer cancel()
+
+			_, state, err := c.Check(ctx, "127.0.0.1", 80)
+			if !errors.Is(err, ErrAgentUnavailable) ||
+				state != steward.StatusError {
+				log.Fatalln("Hung agent is not unavailable:",
+					state, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if time.Since(start) > time.Second {
+		log.Fatalln("Jobs are blocked by dial:", time.Since(start))
+	}
+
+	ln.Close()
+
+	_, _, err = c.Check(context.Background(), "127.0.0.1", 80)
+	if !errors.Is(err, ErrAgentUnavailable) {
+		log.Fatalln("Closed agent is not unavailable:", err)
+	}
+}
+
+func TestAgentAddr(*testing.T) {
+
+	SetAgent(9100, nil)
+
+	if agentAddr("10.1.0.2") != "10.1.0.2:9100" ||
+		agentAddr("10.1.0.2:9200") != "10.1.0.2:9200" ||
+		agentAddr("fd00::2") != "[fd00::2]:9100" {
+		log.Fatalln("Invalid agent address")
+	}
+}
+
+func TestAgentRejectChecker(*testing.T) {
+
+	dir := checkersDir("FooService", `echo '{"status": "up"}'`)
+
+	defer os.RemoveAll(dir)
+
+	certs := testCerts()
+
+	defer os.RemoveAll(certs)
+
+	ln := startAgent(certs, dir)
+
+	defer ln.Close()
+
+	for _, service := range []string{"../../../bin/true", "/bin/true",
+		"..", "", "BarService"} {
+
+		c := AgentChecker{Addr: ln.Addr().String(), TLS: juryTLS(certs),
+			Service: service, JSONProtocol: true}
+
+		res, state, err := c.Check(context.Background(), "127.0.0.1", 80)
+		if !errors.Is(err, ErrAgentUnavailable) ||
+			state != steward.StatusError ||
+			!strings.Contains(res.Private, "agent rejects checker") {
+			log.Fatalln("Checker", service, "is not rejected:",
+				res, state, err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+/**
+ * @file builtin.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief built-in checkers and probes
+ *
+ * Built-in Go checkers and probes of game are listed here. Register is
+ * called by daemon and tfhctl, so both of them run the same checkers.
+ */
+
+package builtin
+
+import "github.com/jollheef/tin_foil_hat/checker"
+
+// Checkers of game, by name used in checker field of service
+var Checkers = map[string]checker.Checker{
+	"dummy": Dummy{},
+}
+
+// Probes of game in addition to probes of checker package, by name used in
+// probe field of service
+var Probes = map[string]checker.Probe{}
+
+// Register add built-in checkers and probes
+func Register() {
+
+	for name, c := range Checkers {
+		checker.Register(name, c)
+	}
+
+	for name, p := range Probes {
+		checker.RegisterProbe(name, p)
+	}
+}
@@ -0,0 +1,269 @@
+/**
+ * @file dummy.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief built-in checker of dummy service
+ *
+ * Go version of checker/python-api/dummy_checker.py for sample service
+ * checker/python-api/dummy_service.py. Service store data of user, and
+ * each message of protocol is answered by OK line.
+ */
+
+package builtin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/jollheef/tin_foil_hat/checker"
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Dummy is checker of dummy service
+type Dummy struct{}
+
+// dummyError is failure of service
+type dummyError struct {
+	state   steward.ServiceState
+	public  string // message for team
+	private string // message for jury
+}
+
+func (e dummyError) Error() string {
+	return e.public + ": " + e.private
+}
+
+func fail(state steward.ServiceState, public string, err error) error {
+
+	e := dummyError{state: state, public: public}
+	if err != nil {
+		e.private = err.Error()
+	}
+
+	return e
+}
+
+type dummyConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func dummyDial(ctx context.Context, addr string, port int) (c dummyConn,
+	err error) {
+
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp",
+		net.JoinHostPort(addr, strconv.Itoa(port)))
+	if err != nil {
+		err = fail(steward.StatusDown, "connection failed", err)
+		return
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c = dummyConn{conn, bufio.NewReader(conn)}
+
+	return
+}
+
+// readLine returns line of reply without newline
+func (c dummyConn) readLine() (line string, err error) {
+	line, err = c.reader.ReadString('\n')
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+// expectOK send message, service is in state if reply is not OK
+func (c dummyConn) expectOK(msg string, state steward.ServiceState,
+	public string) (err error) {
+
+	_, err = io.WriteString(c, msg)
+	if err != nil {
+		return fail(steward.StatusMumble, public, err)
+	}
+
+	reply, err := c.readLine()
+	if err != nil {
+		return fail(steward.StatusMumble, public, err)
+	}
+
+	if reply != "OK" {
+		return fail(state, public,
+			fmt.Errorf("reply '%s' to '%s'", reply, msg))
+	}
+
+	return
+}
+
+func randomString(symbols string, n int) string {
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = symbols[rand.Intn(len(symbols))]
+	}
+
+	return string(b)
+}
+
+func (d Dummy) register(ctx context.Context, addr string, port int,
+	login, password string) (err error) {
+
+	c, err := dummyDial(ctx, addr, port)
+	if err != nil {
+		return
+	}
+
+	defer c.Close()
+
+	for _, msg := range []string{"REG\n", login, password} {
+		err = c.expectOK(msg, steward.StatusMumble,
+			"registration failed")
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (d Dummy) put(ctx context.Context, addr string, port int,
+	data string) (cred string, err error) {
+
+	login := randomString("abcdefghijklmnopqrstuvwxyz"+
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ", 10)
+	password := randomString("0123456789", 10)
+
+	err = d.register(ctx, addr, port, login, password)
+	if err != nil {
+		return
+	}
+
+	c, err := dummyDial(ctx, addr, port)
+	if err != nil {
+		return
+	}
+
+	defer c.Close()
+
+	for _, msg := range []string{"PUT\n", login, password, data} {
+		err = c.expectOK(msg, steward.StatusMumble, "put failed")
+		if err != nil {
+			return
+		}
+	}
+
+	cred = login + ":" + password
+
+	return
+}
+
+func (d Dummy) get(ctx context.Context, addr string, port int,
+	cred string) (data string, err error) {
+
+	login, password, ok := strings.Cut(cred, ":")
+	if !ok {
+		return "", fmt.Errorf("invalid cred '%s'", cred)
+	}
+
+	c, err := dummyDial(ctx, addr, port)
+	if err != nil {
+		return
+	}
+
+	defer c.Close()
+
+	err = c.expectOK("GET\n", steward.StatusMumble, "get failed")
+	if err != nil {
+		return
+	}
+
+	// User of flag is lost
+	for _, msg := range []string{login, password} {
+		err = c.expectOK(msg, steward.StatusCorrupt, "user not found")
+		if err != nil {
+			return
+		}
+	}
+
+	data, err = c.readLine()
+	if err != nil || data == "NOOK" {
+		return "", fail(steward.StatusCorrupt, "data not found", err)
+	}
+
+	reply, err := c.readLine()
+	if err != nil || reply != "OK" {
+		return "", fail(steward.StatusMumble, "get failed", err)
+	}
+
+	return
+}
+
+// result returns result of checker, failure of service is not error
+func result(res checker.Result, err error) (checker.Result,
+	steward.ServiceState, error) {
+
+	if e, ok := err.(dummyError); ok {
+		res.Public, res.Private = e.public, e.private
+		return res, e.state, nil
+	}
+
+	if err != nil {
+		res.Private = err.Error()
+		return res, steward.StatusError, err
+	}
+
+	return res, steward.StatusUP, nil
+}
+
+// Put flag to service
+func (d Dummy) Put(ctx context.Context, addr string, port, vuln int,
+	flag string) (checker.Result, steward.ServiceState, error) {
+
+	var res checker.Result
+
+	cred, err := d.put(ctx, addr, port, flag)
+	res.Cred = cred
+
+	return result(res, err)
+}
+
+// Get flag from service
+func (d Dummy) Get(ctx context.Context, addr string, port, vuln int,
+	cred string) (checker.Result, steward.ServiceState, error) {
+
+	var res checker.Result
+
+	flag, err := d.get(ctx, addr, port, cred)
+	res.Flag = flag
+
+	return result(res, err)
+}
+
+// Check service logic, data is put and got at once
+func (d Dummy) Check(ctx context.Context, addr string, port int) (
+	checker.Result, steward.ServiceState, error) {
+
+	data := randomString("0123456789", 10)
+
+	cred, err := d.put(ctx, addr, port, data)
+	if err != nil {
+		return result(checker.Result{}, err)
+	}
+
+	got, err := d.get(ctx, addr, port, cred)
+	if err == nil && got != data {
+		err = fail(steward.StatusMumble, "data changed",
+			fmt.Errorf("got '%s' instead '%s'", got, data))
+	}
+
+	return result(checker.Result{}, err)
+}
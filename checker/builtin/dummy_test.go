@@ -0,0 +1,166 @@
+/**
+ * @file dummy_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test built-in checker of dummy service
+ */
+
+package builtin
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/checker"
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// dummyService is Go version of dummy_service.py
+type dummyService struct {
+	mutex sync.Mutex
+	users map[string]string
+	data  map[string]string
+}
+
+func (s *dummyService) handle(conn net.Conn) {
+
+	defer conn.Close()
+
+	buf := make([]byte, 1024)
+	recv := func() string {
+		n, _ := conn.Read(buf)
+		return string(buf[:n])
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch cmd := recv(); cmd {
+	case "REG\n":
+		io.WriteString(conn, "OK\n")
+
+		login := recv()
+		if _, ok := s.users[login]; ok {
+			io.WriteString(conn, "EXIST\n")
+			return
+		}
+		io.WriteString(conn, "OK\n")
+
+		s.users[login] = recv()
+		io.WriteString(conn, "OK\n")
+
+	case "PUT\n", "GET\n":
+		io.WriteString(conn, "OK\n")
+
+		login := recv()
+		password, ok := s.users[login]
+		if !ok {
+			io.WriteString(conn, "INCORRECT\n")
+			return
+		}
+		io.WriteString(conn, "OK\n")
+
+		if recv() != password {
+			io.WriteString(conn, "INCORRECT\n")
+			return
+		}
+		io.WriteString(conn, "OK\n")
+
+		if cmd == "PUT\n" {
+			s.data[login] = recv()
+			io.WriteString(conn, "OK\n")
+		} else if data, ok := s.data[login]; ok {
+			io.WriteString(conn, data+"\nOK\n")
+		} else {
+			io.WriteString(conn, "NOOK\n")
+		}
+
+	default:
+		io.WriteString(conn, "NOOK\n")
+	}
+}
+
+func runDummyService() (port int, stop func()) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalln("Listen failed:", err)
+	}
+
+	s := &dummyService{users: make(map[string]string),
+		data: make(map[string]string)}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+
+	port = listener.Addr().(*net.TCPAddr).Port
+
+	return port, func() { listener.Close() }
+}
+
+func TestDummyChecker(*testing.T) {
+
+	Register()
+
+	if !checker.Registered("dummy") {
+		log.Fatalln("Dummy checker is not registered")
+	}
+
+	port, stop := runDummyService()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+
+	defer cancel()
+
+	var d Dummy
+
+	flag := "c01d4567c01d4567c01d4567c01d4567="
+
+	res, state, err := d.Put(ctx, "127.0.0.1", port, 1, flag)
+	if err != nil || state != steward.StatusUP || res.Cred == "" {
+		log.Fatalln("Put failed:", res, state, err)
+	}
+
+	cred := res.Cred
+
+	res, state, err = d.Get(ctx, "127.0.0.1", port, 1, cred)
+	if err != nil || state != steward.StatusUP || res.Flag != flag {
+		log.Fatalln("Get failed:", res, state, err)
+	}
+
+	_, state, err = d.Check(ctx, "127.0.0.1", port)
+	if err != nil || state != steward.StatusUP {
+		log.Fatalln("Check failed:", state, err)
+	}
+
+	// Flag of unknown user is lost
+	res, state, err = d.Get(ctx, "127.0.0.1", port, 1, "nobody:42")
+	if err != nil || state != steward.StatusCorrupt || res.Public == "" {
+		log.Fatalln("Lost flag is not corrupt:", res, state, err)
+	}
+
+	_, state, err = d.Get(ctx, "127.0.0.1", port, 1, "invalid")
+	if err == nil || state != steward.StatusError {
+		log.Fatalln("Invalid cred is not checker error:", state, err)
+	}
+
+	stop()
+
+	_, state, err = d.Check(ctx, "127.0.0.1", port)
+	if err != nil || state != steward.StatusDown {
+		log.Fatalln("Stopped service is not down:", state, err)
+	}
+}
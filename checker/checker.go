@@ -6,138 +6,252 @@
  * @brief functions for check services
  *
  * Provide functions for check service status, put flags and check flags.
+ * Flags of current round are checked always, random flags from previous
+ * rounds are checked if it is enabled (see SetOldFlags).
  */
 
 package checker
 
 import (
-	"crypto/rsa"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"net"
-	"sync"
+	"math/rand"
+	"strings"
 
 	"github.com/jollheef/tin_foil_hat/steward"
 	"github.com/jollheef/tin_foil_hat/vexillary"
 )
 
-func tcpPortOpen(team steward.Team, svc steward.Service) bool {
+// worst returns worst of states, states are ordered from up to error
+func worst(a, b steward.ServiceState) steward.ServiceState {
+	if b > a {
+		return b
+	}
+	return a
+}
 
-	addr := fmt.Sprintf("%s:%d", team.Vulnbox, svc.Port)
+// newStatus returns status with info about all runs of checker
+func newStatus(round int, team steward.Team, svc steward.Service,
+	state steward.ServiceState, probeErr error,
+	runs ...Result) (status steward.Status) {
 
-	conn, err := net.DialTimeout("tcp", addr, portCheckTimeout)
-	if err != nil {
-		return false
+	status = steward.Status{Round: round, TeamID: team.ID,
+		ServiceID: svc.ID, State: state, Logs: reports(probeErr, runs...)}
+
+	var messages []string
+
+	for _, res := range runs {
+		if res.Command == "" {
+			continue
+		}
+
+		if res.Public != "" {
+			messages = append(messages, res.Public)
+		}
+
+		status.ExitCode = res.ExitCode
+		status.Duration += res.Duration
 	}
 
-	conn.Close()
-	return true
+	status.Message = strings.Join(messages, "; ")
+
+	return
 }
 
-func putFlag(db *sql.DB, priv *rsa.PrivateKey, round int, team steward.Team,
-	svc steward.Service) (err error) {
+func putVulnFlag(round int, team steward.Team, svc steward.Service, vuln int,
+	flag string) (state steward.ServiceState, res Result, err error) {
 
-	flag, err := vexillary.GenerateFlag(priv)
+	res, state, err = run(team, svc, fmt.Sprintf("put vuln %d", vuln),
+		func(ctx context.Context, c Checker) (Result,
+			steward.ServiceState, error) {
+			return c.Put(ctx, team.Vulnbox, svc.Port, vuln, flag)
+		})
 	if err != nil {
-		log.Println("Generate flag failed:", err)
+		log.Println("Put flag to service failed:", err)
 		return
 	}
 
-	portOpen := true
-	if !svc.UDP {
-		portOpen = tcpPortOpen(team, svc)
+	if state != steward.StatusUP {
+		log.Printf("Put flag, round %d, team %s, service %s, vuln %d: %s",
+			round, team.Name, svc.Name, vuln, res.logs())
 	}
 
-	var cred, logs string
-	var state steward.ServiceState
-	if portOpen {
-		if team.UseNetbox {
-			cred, logs, state, err = sshPut(team.Netbox,
-				svc.CheckerPath, team.Vulnbox, svc.Port, flag)
-		} else {
-			cred, logs, state, err = put(svc.CheckerPath,
-				team.Vulnbox, svc.Port, flag)
-		}
+	return
+}
+
+func putFlag(db *sql.DB, key vexillary.Key, round int, team steward.Team,
+	svc steward.Service) (err error) {
+
+	probeErr := probe(team, svc)
+
+	var runs []Result
+	var flags []steward.Flag
+
+	state := steward.StatusUP
+	if probeErr != nil {
+		state = steward.StatusDown
+	}
+
+	for vuln := 1; vuln <= steward.VulnCount(svc); vuln++ {
+
+		flag, err := vexillary.GenerateFlag(key, round, team.ID,
+			svc.ID)
 		if err != nil {
-			log.Println("Put flag to service failed:", err)
-			return
+			log.Println("Generate flag failed:", err)
+			return err
 		}
 
-		if state != steward.StatusUP {
-			log.Printf("Put flag, round %d, team %s, service %s: %s",
-				round, team.Name, svc.Name, logs)
+		var res Result
+		if probeErr == nil {
+			var vulnState steward.ServiceState
+
+			vulnState, res, err = putVulnFlag(round, team, svc,
+				vuln, flag)
+			if err != nil {
+				// Failure of jury (e.g. agent is unavailable),
+				// status and flags are not stored
+				return err
+			}
+
+			state = worst(state, vulnState)
 		}
-	} else {
-		state = steward.StatusDown
+
+		runs = append(runs, res)
+		flags = append(flags, steward.Flag{ID: -1, Flag: flag,
+			Round: round, TeamID: team.ID, ServiceID: svc.ID,
+			Cred: res.Cred, Vuln: vuln, FlagID: res.FlagID})
 	}
 
 	err = steward.PutStatus(db,
-		steward.Status{round, team.ID, svc.ID, state})
+		newStatus(round, team, svc, state, probeErr, runs...))
 	if err != nil {
 		log.Println("Add status to database failed:", err)
 		return
 	}
 
-	err = steward.AddFlag(db,
-		steward.Flag{-1, flag, round, team.ID, svc.ID, cred})
-	if err != nil {
-		log.Println("Add flag to database failed:", err)
-		return
+	for _, flg := range flags {
+		err = steward.AddFlag(db, flg)
+		if err != nil {
+			log.Println("Add flag to database failed:", err)
+			return
+		}
 	}
 
 	return
 }
 
-func getFlag(db *sql.DB, round int, team steward.Team,
-	svc steward.Service) (state steward.ServiceState, err error) {
+var (
+	oldFlagRounds  = 0 // count of previous rounds for check flags
+	oldFlagSamples = 0 // count of flags from previous rounds to check
+)
+
+// SetOldFlags set count of previous rounds and count of random flags from
+// them, which are checked in addition to flags of current round. Rounds
+// should be less than flag lifetime.
+func SetOldFlags(rounds, samples int) {
+	oldFlagRounds = rounds
+	oldFlagSamples = samples
+}
+
+// oldFlags returns random sample of flags from previous rounds, which was
+// successfully checked in their round
+func oldFlags(db *sql.DB, round int, team steward.Team,
+	svc steward.Service) (flgs []steward.Flag, err error) {
 
-	flag, cred, err := steward.GetCred(db, round, team.ID, svc.ID)
+	if oldFlagRounds <= 0 || oldFlagSamples <= 0 {
+		return
+	}
+
+	flgs, err = steward.GetCheckedFlags(db, round-oldFlagRounds, round-1,
+		team.ID, svc.ID)
 	if err != nil {
-		log.Println("Get cred failed:", err)
-		state = steward.StatusCorrupt
 		return
 	}
 
-	var logs string
-	var serviceFlag string
+	rand.Shuffle(len(flgs), func(i, j int) {
+		flgs[i], flgs[j] = flgs[j], flgs[i]
+	})
 
-	if team.UseNetbox {
-		serviceFlag, logs, state, err = sshGet(team.Netbox,
-			svc.CheckerPath, team.Vulnbox, svc.Port, cred)
-	} else {
-		serviceFlag, logs, state, err = get(svc.CheckerPath,
-			team.Vulnbox, svc.Port, cred)
+	if len(flgs) > oldFlagSamples {
+		flgs = flgs[:oldFlagSamples]
 	}
+
+	return
+}
+
+func getFlag(round int, team steward.Team, svc steward.Service,
+	flg steward.Flag) (state steward.ServiceState, res Result, err error) {
+
+	res, state, err = run(team, svc,
+		fmt.Sprintf("get vuln %d round %d", flg.Vuln, flg.Round),
+		func(ctx context.Context, c Checker) (Result,
+			steward.ServiceState, error) {
+			return c.Get(ctx, team.Vulnbox, svc.Port, flg.Vuln,
+				flg.Cred)
+		})
 	if err != nil {
 		log.Println("Check service failed:", err)
 		return
 	}
 
-	if flag != serviceFlag {
+	if flg.Flag != res.Flag {
 		state = steward.StatusCorrupt
 	}
 
 	if state != steward.StatusUP {
-		log.Printf("Get flag, round %d, team %s, service %s: %s",
-			round, team.Name, svc.Name, logs)
+		log.Printf("Get flag, round %d, team %s, service %s, vuln %d, "+
+			"flag of round %d: %s", round, team.Name, svc.Name,
+			flg.Vuln, flg.Round, res.logs())
 	}
 
 	return
 }
 
-func checkService(db *sql.DB, round int, team steward.Team,
-	svc steward.Service) (state steward.ServiceState, err error) {
+// flagsToCheck returns flags of current round for each vuln and sample of
+// flags from previous rounds
+func flagsToCheck(db *sql.DB, round int, team steward.Team,
+	svc steward.Service) (flgs []steward.Flag, err error) {
+
+	for vuln := 1; vuln <= steward.VulnCount(svc); vuln++ {
+		flg := steward.Flag{Round: round, TeamID: team.ID,
+			ServiceID: svc.ID, Vuln: vuln}
+
+		flg.Flag, flg.Cred, err = steward.GetCred(db, round, team.ID,
+			svc.ID, vuln)
+		if err == sql.ErrNoRows {
+			// Flag is not put by failure of jury
+			err = nil
+			continue
+		}
+		if err != nil {
+			return
+		}
 
-	var logs string
+		flgs = append(flgs, flg)
+	}
 
-	if team.UseNetbox {
-		state, logs, err = sshCheck(team.Netbox, svc.CheckerPath,
-			team.Vulnbox, svc.Port)
-	} else {
-		state, logs, err = check(svc.CheckerPath, team.Vulnbox,
-			svc.Port)
+	old, err := oldFlags(db, round, team, svc)
+	if err != nil {
+		return
 	}
+
+	flgs = append(flgs, old...)
+
+	return
+}
+
+func checkService(db *sql.DB, round int, team steward.Team,
+	svc steward.Service) (state steward.ServiceState, res Result,
+	err error) {
+
+	res, state, err = run(team, svc, "chk",
+		func(ctx context.Context, c Checker) (Result,
+			steward.ServiceState, error) {
+			return c.Check(ctx, team.Vulnbox, svc.Port)
+		})
 	if err != nil {
 		log.Println("Check service failed:", err)
 		return
@@ -145,79 +259,168 @@ func checkService(db *sql.DB, round int, team steward.Team,
 
 	if state != steward.StatusUP {
 		log.Printf("Check, round %d, team %s, service %s: %s",
-			round, team.Name, svc.Name, logs)
+			round, team.Name, svc.Name, res.logs())
 	}
 
 	return
 }
 
-// Check service status and flag if it's exist.
-func checkFlag(db *sql.DB, round int, team steward.Team, svc steward.Service,
-	wg *sync.WaitGroup) {
+// reports returns reports of all runs of checker
+func reports(probeErr error, runs ...Result) (logs string) {
 
-	defer wg.Done()
+	if probeErr != nil {
+		return probeErr.Error() + "\n"
+	}
 
-	// Check service port open
-	portOpen := true
-	if !svc.UDP {
-		portOpen = tcpPortOpen(team, svc)
+	for _, res := range runs {
+		if res.Command != "" {
+			logs += res.report()
+		}
+	}
+
+	// Many runs (e.g. many flag stores) can exceed each output limit
+	if len(logs) > totalLogsLimit {
+		logs = steward.TruncateText(logs, totalLogsLimit) +
+			"\n[truncated]\n"
 	}
 
+	return
+}
+
+// Check service status and flag if it's exist.
+func checkFlag(db *sql.DB, round int, team steward.Team,
+	svc steward.Service) {
+
+	// Check service is alive
+	probeErr := probe(team, svc)
+
 	var state steward.ServiceState
-	if portOpen {
+	var runs []Result
+	var err error
+	if probeErr == nil {
+		var chk Result
+
 		// First check service logic
-		state, _ = checkService(db, round, team, svc)
+		state, chk, err = checkService(db, round, team, svc)
+		runs = append(runs, chk)
+
 		if state == steward.StatusUP {
-			// If logic is correct, do flag check
-			state, _ = getFlag(db, round, team, svc)
+			// If logic is correct, do check of flags
+			var flgs []steward.Flag
+
+			flgs, err = flagsToCheck(db, round, team, svc)
+			if err != nil {
+				// Failure of jury database, not service
+				log.Printf("Check, round %d, team %s, "+
+					"service %s: get flags failed, status "+
+					"is not stored: %s", round, team.Name,
+					svc.Name, err)
+				return
+			}
+
+			for _, flg := range flgs {
+				var get Result
+				var flgState steward.ServiceState
+
+				flgState, get, err = getFlag(round, team, svc,
+					flg)
+				if errors.Is(err, ErrAgentUnavailable) {
+					break
+				}
+
+				runs = append(runs, get)
+				state = worst(state, flgState)
+			}
 		}
 	} else {
 		state = steward.StatusDown
 	}
 
-	err := steward.PutStatus(db, steward.Status{round,
-		team.ID, svc.ID, state})
+	if errors.Is(err, ErrAgentUnavailable) {
+		log.Printf("Check, round %d, team %s, service %s: status "+
+			"is not stored: %s", round, team.Name, svc.Name, err)
+		return
+	}
+
+	err = steward.PutStatus(db,
+		newStatus(round, team, svc, state, probeErr, runs...))
 	if err != nil {
 		log.Println("Add status failed:", err)
 		return
 	}
 }
 
-// PutFlags put flags to services
-func PutFlags(db *sql.DB, priv *rsa.PrivateKey, round int,
+// PutFlags put flags to services (see SetLimits and SetJitter)
+func PutFlags(db *sql.DB, key vexillary.Key, round int,
 	teams []steward.Team, services []steward.Service) (err error) {
 
-	var wg sync.WaitGroup
-
-	for _, team := range teams {
-		for _, svc := range services {
-			wg.Add(1)
-			go func(team steward.Team, svc steward.Service) {
-				defer wg.Done()
-				putFlag(db, priv, round, team, svc)
-			}(team, svc)
-		}
-	}
-
-	wg.Wait()
+	schedule(teams, services, func(team steward.Team, svc steward.Service) {
+		putFlag(db, key, round, team, svc)
+	})
 
 	return
 }
 
-// CheckFlags check flags in services
+// CheckFlags check flags in services (see SetLimits and SetJitter)
 func CheckFlags(db *sql.DB, round int, teams []steward.Team,
 	services []steward.Service) (err error) {
 
-	var wg sync.WaitGroup
+	schedule(teams, services, func(team steward.Team, svc steward.Service) {
+		checkFlag(db, round, team, svc)
+	})
+
+	return
+}
+
+// SelfTest put flags to service of team, check service and get flags back
+// in the same way as in round, without database. Returned status contains
+// full logs of checker.
+func SelfTest(key vexillary.Key, team steward.Team,
+	svc steward.Service) (status steward.Status, err error) {
+
+	probeErr := probe(team, svc)
+	if probeErr != nil {
+		status = newStatus(0, team, svc, steward.StatusDown, probeErr)
+		return
+	}
+
+	var runs []Result
+	var flgs []steward.Flag
+
+	state := steward.StatusUP
+
+	for vuln := 1; vuln <= steward.VulnCount(svc); vuln++ {
+
+		flag, err := vexillary.GenerateFlag(key, 0, team.ID, svc.ID)
+		if err != nil {
+			return status, err
+		}
+
+		putState, put, err := putVulnFlag(0, team, svc, vuln, flag)
+		if err != nil {
+			return status, err
+		}
+
+		runs = append(runs, put)
+		state = worst(state, putState)
+
+		flgs = append(flgs, steward.Flag{Flag: flag, Cred: put.Cred,
+			Vuln: vuln})
+	}
+
+	chkState, chk, _ := checkService(nil, 0, team, svc)
+	runs = append(runs, chk)
+	state = worst(state, chkState)
 
-	for _, team := range teams {
-		for _, svc := range services {
-			wg.Add(1)
-			go checkFlag(db, round, team, svc, &wg)
+	if state == steward.StatusUP {
+		for _, flg := range flgs {
+			flgState, get, _ := getFlag(0, team, svc, flg)
+			runs = append(runs, get)
+			state = worst(state, flgState)
 		}
 	}
 
-	wg.Wait()
+	status = newStatus(0, team, svc, state, probeErr, runs...)
 
 	return
 }
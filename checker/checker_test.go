@@ -165,7 +165,7 @@ func TestFlagsWork(t *testing.T) {
 	service := newDummyService("python-api/dummy_service.py", port)
 	service.Stop() // if already run
 
-	priv, err := vexillary.GenerateKey()
+	key, err := vexillary.GenerateKey()
 	if err != nil {
 		log.Fatalln("Generate key failed:", err)
 	}
@@ -199,7 +199,7 @@ func TestFlagsWork(t *testing.T) {
 		log.Fatalln("Get services failed:", err)
 	}
 
-	err = checker.PutFlags(db.db, priv, round, teams, services)
+	err = checker.PutFlags(db.db, key, round, teams, services)
 	if err != nil {
 		log.Fatalln("Put flags failed:", err)
 	}
@@ -214,7 +214,7 @@ func TestFlagsWork(t *testing.T) {
 
 	service.BrokeLogic()
 
-	err = checker.PutFlags(db.db, priv, round, teams, services)
+	err = checker.PutFlags(db.db, key, round, teams, services)
 	if err != nil {
 		log.Fatalln("Put flags failed:", err)
 	}
@@ -232,7 +232,7 @@ func TestFlagsWork(t *testing.T) {
 
 	log.Println("Put flags to correct service...")
 
-	err = checker.PutFlags(db.db, priv, round, teams, services)
+	err = checker.PutFlags(db.db, key, round, teams, services)
 	if err != nil {
 		log.Fatalln("Put flags failed:", err)
 	}
@@ -0,0 +1,227 @@
+/**
+ * @file interface.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief checker interface
+ *
+ * Provide checker interface and registry of built-in checkers. Service use
+ * built-in checker if name of checker is set, agent on netbox for teams
+ * with netbox, pool of checker workers if count of workers is set, or
+ * checker executable otherwise.
+ */
+
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Result contains result of checker call
+type Result struct {
+	Status  string `json:"status"`  // used only by json protocol
+	Public  string `json:"public"`  // message for team
+	Private string `json:"private"` // message for jury
+	Cred    string `json:"cred"`    // returned by put
+	FlagID  string `json:"flag_id"` // returned by put, public for teams
+	Flag    string `json:"flag"`    // returned by get
+
+	// Raw checker run info
+	Command  string        `json:"-"`
+	Stdout   string        `json:"-"`
+	Stderr   string        `json:"-"`
+	ExitCode int           `json:"-"`
+	Duration time.Duration `json:"-"`
+}
+
+const (
+	logsLimit      = 16 * 1024  // max size of each output in report
+	totalLogsLimit = 128 * 1024 // max size of all reports of status
+)
+
+// limit returns valid text of output, cut on rune boundary
+func limit(s string) string {
+	s = steward.ValidText(s)
+	if len(s) > logsLimit {
+		return steward.TruncateText(s, logsLimit) + "\n[truncated]\n"
+	}
+	return s
+}
+
+// logs returns messages for jury
+func (res Result) logs() string {
+	if res.Private == "" {
+		return res.Stderr
+	}
+	return res.Private + "\n" + res.Stderr
+}
+
+// report returns full output of checker run for jury
+func (res Result) report() string {
+	return fmt.Sprintf("$ %s\n[exit code %d, %s]\n"+
+		"[private]\n%s\n[stdout]\n%s\n[stderr]\n%s\n", res.Command,
+		res.ExitCode, res.Duration, limit(res.Private),
+		limit(res.Stdout), limit(res.Stderr))
+}
+
+// Checker put flag to flag store (vuln, from 1) of service, get flag from
+// it and check service logic. Checker must return as soon as context is done.
+type Checker interface {
+	Put(ctx context.Context, addr string, port, vuln int, flag string) (
+		res Result, state steward.ServiceState, err error)
+	Get(ctx context.Context, addr string, port, vuln int, cred string) (
+		res Result, state steward.ServiceState, err error)
+	Check(ctx context.Context, addr string, port int) (
+		res Result, state steward.ServiceState, err error)
+}
+
+var (
+	checkers      = make(map[string]Checker)
+	checkersMutex sync.Mutex
+)
+
+// Register add built-in checker, which can be used by service instead of
+// checker executable
+func Register(name string, c Checker) {
+
+	checkersMutex.Lock()
+
+	defer checkersMutex.Unlock()
+
+	checkers[name] = c
+}
+
+// Registered returns true if built-in checker with name exist
+func Registered(name string) bool {
+
+	checkersMutex.Lock()
+
+	defer checkersMutex.Unlock()
+
+	_, ok := checkers[name]
+	return ok
+}
+
+// ValidService returns error if built-in checker or probe of service is
+// not registered
+func ValidService(svc steward.Service) (err error) {
+
+	if svc.Checker != "" && !Registered(svc.Checker) {
+		return errors.New("checker " + svc.Checker + " of service " +
+			svc.Name + " is not registered")
+	}
+
+	if svc.Probe != "" && !ProbeRegistered(svc.Probe) {
+		return errors.New("probe " + svc.Probe + " of service " +
+			svc.Name + " is not registered")
+	}
+
+	// Invalid regexp fails probe of every team
+	name := serviceProbe(svc)
+	if name == "banner" || name == "udp" {
+		_, err = expectRegexp(svc.ProbeExpect)
+		if err != nil {
+			return fmt.Errorf("invalid probe expect of service %s: %s",
+				svc.Name, err)
+		}
+	}
+
+	return
+}
+
+// serviceChecker returns checker of service for team
+func serviceChecker(team steward.Team, svc steward.Service) (c Checker,
+	err error) {
+
+	if svc.Checker != "" {
+
+		checkersMutex.Lock()
+
+		defer checkersMutex.Unlock()
+
+		c, ok := checkers[svc.Checker]
+		if !ok {
+			err = errors.New("checker " + svc.Checker +
+				" is not registered")
+		}
+		return c, err
+	}
+
+	if team.UseNetbox {
+		c = AgentChecker{Addr: agentAddr(team.Netbox),
+			TLS: agentTLS, Service: svc.Name,
+			JSONProtocol: svc.JSONProtocol, Vulns: svc.Vulns}
+		return
+	}
+
+	if svc.Workers > 0 {
+		c = servicePool(svc)
+		return
+	}
+
+	c = ExecChecker{Path: svc.CheckerPath,
+		JSONProtocol: svc.JSONProtocol, Vulns: svc.Vulns}
+
+	return
+}
+
+// run call checker with timeout, checker which ignores context is
+// considered down after timeout
+func run(team steward.Team, svc steward.Service, command string,
+	call func(ctx context.Context, c Checker) (Result,
+		steward.ServiceState, error)) (res Result,
+	state steward.ServiceState, err error) {
+
+	c, err := serviceChecker(team, svc)
+	if err != nil {
+		state = steward.StatusError
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	defer cancel()
+
+	type callResult struct {
+		res   Result
+		state steward.ServiceState
+		err   error
+	}
+
+	done := make(chan callResult, 1)
+
+	start := time.Now()
+
+	go func() {
+		var r callResult
+		r.res, r.state, r.err = call(ctx, c)
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		res, state, err = r.res, r.state, r.err
+	case <-ctx.Done():
+		select {
+		case r := <-done: // killed by timeout
+			res, state, err = r.res, r.state, r.err
+		default:
+			res.Private = "checker does not return after timeout"
+			state = steward.StatusDown
+		}
+	}
+
+	res.Duration = time.Since(start)
+
+	if res.Command == "" {
+		res.Command = fmt.Sprintf("%s %s", svc.Checker, command)
+	}
+
+	return
+}
@@ -0,0 +1,129 @@
+/**
+ * @file interface_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test built-in checkers
+ */
+
+package checker
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// testChecker returns state for each call, or hangs if hang is set
+type testChecker struct {
+	state steward.ServiceState
+	hang  bool
+}
+
+func (c testChecker) result() (res Result, state steward.ServiceState,
+	err error) {
+
+	if c.hang {
+		time.Sleep(time.Hour)
+	}
+
+	res.Public = "test"
+	res.Cred = "cred"
+	state = c.state
+	return
+}
+
+func (c testChecker) Put(ctx context.Context, addr string, port, vuln int,
+	flag string) (Result, steward.ServiceState, error) {
+	return c.result()
+}
+
+func (c testChecker) Get(ctx context.Context, addr string, port, vuln int,
+	cred string) (Result, steward.ServiceState, error) {
+	return c.result()
+}
+
+func (c testChecker) Check(ctx context.Context, addr string, port int) (
+	Result, steward.ServiceState, error) {
+	return c.result()
+}
+
+func TestBuiltinChecker(*testing.T) {
+
+	Register("test", testChecker{state: steward.StatusMumble})
+
+	if !Registered("test") || Registered("unknown") {
+		log.Fatalln("Invalid registry")
+	}
+
+	team := steward.Team{Vulnbox: "127.0.0.1"}
+	svc := steward.Service{Port: 80, Checker: "test"}
+
+	res, state, err := run(team, svc, "chk",
+		func(ctx context.Context, c Checker) (Result,
+			steward.ServiceState, error) {
+			return c.Check(ctx, team.Vulnbox, svc.Port)
+		})
+	if err != nil || state != steward.StatusMumble ||
+		res.Public != "test" || res.Command != "test chk" {
+		log.Fatalln("Invalid built-in checker result:", res, state, err)
+	}
+
+	svc.Checker = "unknown"
+
+	_, state, err = run(team, svc, "chk",
+		func(ctx context.Context, c Checker) (Result,
+			steward.ServiceState, error) {
+			return c.Check(ctx, team.Vulnbox, svc.Port)
+		})
+	if err == nil || state != steward.StatusError {
+		log.Fatalln("Unknown checker is used")
+	}
+}
+
+func TestValidService(*testing.T) {
+
+	Register("valid", testChecker{})
+
+	for svc, valid := range map[steward.Service]bool{
+		{Name: "exec"}:                           true,
+		{Name: "builtin", Checker: "valid"}:      true,
+		{Name: "probe", Probe: "http"}:           true,
+		{Name: "no checker", Checker: "unknown"}: false,
+		{Name: "no probe", Probe: "unknown"}:     false,
+		{Name: "banner", Probe: "banner",
+			ProbeExpect: "^SSH-"}: true,
+		{Name: "invalid banner", Probe: "banner",
+			ProbeExpect: "^SSH-("}: false,
+		{Name: "invalid udp", Probe: "udp", UDP: true,
+			ProbeExpect: "[a-"}: false,
+	} {
+		if (ValidService(svc) == nil) != valid {
+			log.Fatalln("Invalid validation of", svc.Name)
+		}
+	}
+}
+
+func TestBuiltinCheckerTimeout(*testing.T) {
+
+	Register("hang", testChecker{hang: true})
+
+	SetTimeout(100 * time.Millisecond)
+
+	defer SetTimeout(10 * time.Second)
+
+	team := steward.Team{Vulnbox: "127.0.0.1"}
+	svc := steward.Service{Port: 80, Checker: "hang"}
+
+	_, state, err := run(team, svc, "chk",
+		func(ctx context.Context, c Checker) (Result,
+			steward.ServiceState, error) {
+			return c.Check(ctx, team.Vulnbox, svc.Port)
+		})
+	if err != nil || state != steward.StatusDown {
+		log.Fatalln("Invalid hanging checker result:", state, err)
+	}
+}
@@ -0,0 +1,278 @@
+/**
+ * @file pool.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief pool of persistent checker processes
+ *
+ * Provide checker which keeps long-lived worker processes of checker
+ * executable (started with "worker" argument). Each job is sent to worker
+ * as json line {"id", "command", "host", "port", "vuln", "arg"} on stdin,
+ * worker replies with json line of result (as in json protocol) with same
+ * id on stdout. Worker that crashed, hung or replied to other job is
+ * restarted.
+ */
+
+package checker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+type job struct {
+	ID      uint64 `json:"id"`
+	Command string `json:"command"`
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	Vuln    int    `json:"vuln,omitempty"` // for put and get
+	Arg     string `json:"arg,omitempty"`  // flag for put, cred for get
+}
+
+type reply struct {
+	ID uint64 `json:"id"`
+	Result
+}
+
+type worker struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+func startWorker(path string) (w *worker, err error) {
+
+	w = &worker{cmd: exec.Command(path, "worker")}
+
+	w.cmd.Stderr = log.Writer()
+	w.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	w.stdin, err = w.cmd.StdinPipe()
+	if err != nil {
+		return
+	}
+
+	stdout, err := w.cmd.StdoutPipe()
+	if err != nil {
+		return
+	}
+
+	w.stdout = bufio.NewReader(stdout)
+
+	err = w.cmd.Start()
+
+	return
+}
+
+func (w *worker) kill() {
+	syscall.Kill(-w.cmd.Process.Pid, syscall.SIGKILL)
+	w.cmd.Wait()
+}
+
+// PoolChecker sends jobs to persistent worker processes
+type PoolChecker struct {
+	Path string
+	idle chan *worker // nil worker means not started
+	id   uint64
+	mu   sync.Mutex
+}
+
+// NewPoolChecker create pool of n workers, workers are started on demand
+func NewPoolChecker(path string, n int) (c *PoolChecker) {
+
+	c = &PoolChecker{Path: path, idle: make(chan *worker, n)}
+
+	for i := 0; i < n; i++ {
+		c.idle <- nil
+	}
+
+	return
+}
+
+// Put flag to service
+func (c *PoolChecker) Put(ctx context.Context, addr string, port, vuln int,
+	flag string) (res Result, state steward.ServiceState, err error) {
+
+	return c.do(ctx, job{Command: "put", Host: addr, Port: port,
+		Vuln: vuln, Arg: flag})
+}
+
+// Get flag from service
+func (c *PoolChecker) Get(ctx context.Context, addr string, port, vuln int,
+	cred string) (res Result, state steward.ServiceState, err error) {
+
+	return c.do(ctx, job{Command: "get", Host: addr, Port: port,
+		Vuln: vuln, Arg: cred})
+}
+
+// Check service logic
+func (c *PoolChecker) Check(ctx context.Context, addr string, port int) (
+	res Result, state steward.ServiceState, err error) {
+
+	return c.do(ctx, job{Command: "chk", Host: addr, Port: port})
+}
+
+func (c *PoolChecker) do(ctx context.Context, j job) (res Result,
+	state steward.ServiceState, err error) {
+
+	c.mu.Lock()
+	c.id++
+	j.ID = c.id
+	c.mu.Unlock()
+
+	res.Command = strings.TrimSpace(fmt.Sprintf("%s worker: %s %s %d %s",
+		c.Path, j.Command, j.Host, j.Port, j.Arg))
+
+	var w *worker
+
+	select {
+	case w = <-c.idle:
+	case <-ctx.Done():
+		res.Private = "no free worker before timeout"
+		res.ExitCode = 124
+		state = steward.StatusDown
+		return
+	}
+
+	if w == nil {
+		w, err = startWorker(c.Path)
+		if err != nil {
+			c.idle <- nil
+			state = steward.StatusError
+			return
+		}
+	}
+
+	type readResult struct {
+		line string
+		err  error
+	}
+
+	done := make(chan readResult, 1)
+
+	go func() {
+		var r readResult
+
+		buf, err := json.Marshal(j)
+		if err == nil {
+			_, err = w.stdin.Write(append(buf, '\n'))
+		}
+
+		if err == nil {
+			r.line, err = w.stdout.ReadString('\n')
+		}
+
+		r.err = err
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			// Worker crashed, restart it on next job
+			w.kill()
+			c.idle <- nil
+
+			res.Private = "worker crashed: " + r.err.Error()
+			state = steward.StatusError
+			return
+		}
+
+		res.Stdout = r.line
+
+		var rep reply
+
+		rep, err = decodeReply(j.ID, r.line)
+		if err != nil {
+			// Worker is out of sync, so next jobs would get replies
+			// to other jobs, restart it on next job
+			w.kill()
+			c.idle <- nil
+
+			res.Private = "invalid reply of worker: " + err.Error()
+			state = steward.StatusError
+			return
+		}
+
+		c.idle <- w
+
+		res, state, err = parseReply(res, rep)
+
+	case <-ctx.Done():
+		// Worker hung, restart it on next job
+		w.kill()
+		c.idle <- nil
+
+		res.Private = "worker does not reply before timeout"
+		res.ExitCode = 124
+		state = steward.StatusDown
+	}
+
+	return
+}
+
+// decodeReply returns reply of worker to job with id
+func decodeReply(id uint64, line string) (r reply, err error) {
+
+	err = json.Unmarshal([]byte(line), &r)
+	if err != nil {
+		return
+	}
+
+	if r.ID != id {
+		err = errors.New("reply to other job")
+	}
+
+	return
+}
+
+func parseReply(raw Result, r reply) (res Result,
+	state steward.ServiceState, err error) {
+
+	res = r.Result
+	res.Command, res.Stdout = raw.Command, raw.Stdout
+
+	state = parseStateName(res.Status)
+	if state == steward.StatusUnknown {
+		err = fmt.Errorf("unknown status '%s'", res.Status)
+		return
+	}
+
+	res.Cred = strings.Trim(res.Cred, " \n")
+	res.FlagID = strings.Trim(res.FlagID, " \n")
+	res.Flag = strings.Trim(res.Flag, " \n")
+
+	return
+}
+
+var (
+	pools      = make(map[int]*PoolChecker) // { service id : pool }
+	poolsMutex sync.Mutex
+)
+
+// servicePool returns pool of service, pool is created on first call
+func servicePool(svc steward.Service) (c *PoolChecker) {
+
+	poolsMutex.Lock()
+
+	defer poolsMutex.Unlock()
+
+	c, ok := pools[svc.ID]
+	if !ok {
+		c = NewPoolChecker(svc.CheckerPath, svc.Workers)
+		pools[svc.ID] = c
+	}
+
+	return
+}
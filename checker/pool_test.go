@@ -0,0 +1,100 @@
+/**
+ * @file pool_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test pool of checker workers
+ */
+
+package checker
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Worker replies mumble on check, hangs on put and crashes on get
+const testWorker = `while read -r line; do
+	id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+	case "$line" in
+	*'"command":"chk"'*)
+		echo '{"id":'$id',"status":"mumble","public":"bad"}';;
+	*'"command":"put"'*)
+		sleep 10;;
+	*'"command":"get"'*)
+		exit 1;;
+	esac
+done`
+
+func checkPool(c *PoolChecker) {
+
+	res, state, err := c.Check(context.Background(), "127.0.0.1", 80)
+	if err != nil || state != steward.StatusMumble || res.Public != "bad" {
+		log.Fatalln("Invalid pool check result:", res, state, err)
+	}
+}
+
+func TestPoolChecker(*testing.T) {
+
+	path := writeScript(testWorker)
+
+	defer os.Remove(path)
+
+	c := NewPoolChecker(path, 1)
+
+	checkPool(c)
+	checkPool(c) // same worker
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		200*time.Millisecond)
+
+	defer cancel()
+
+	_, state, _ := c.Put(ctx, "127.0.0.1", 80, 1, "FLAG")
+	if state != steward.StatusDown {
+		log.Fatalln("Invalid hung worker state:", state)
+	}
+
+	checkPool(c) // restarted
+
+	_, state, _ = c.Get(context.Background(), "127.0.0.1", 80, 1,
+		"cred")
+	if state != steward.StatusError {
+		log.Fatalln("Invalid crashed worker state:", state)
+	}
+
+	checkPool(c) // restarted
+}
+
+// Worker replies twice to first job, first reply has other id
+const testDesyncWorker = `while read -r line; do
+	id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+	if [ ! -e "$0.desync" ]; then
+		touch "$0.desync"
+		echo '{"id":0,"status":"up"}'
+	fi
+	echo '{"id":'$id',"status":"mumble","public":"bad"}'
+done`
+
+func TestPoolCheckerDesync(*testing.T) {
+
+	path := writeScript(testDesyncWorker)
+
+	defer os.Remove(path)
+	defer os.Remove(path + ".desync")
+
+	c := NewPoolChecker(path, 1)
+
+	_, state, err := c.Check(context.Background(), "127.0.0.1", 80)
+	if err == nil || state != steward.StatusError {
+		log.Fatalln("Reply to other job accepted:", state, err)
+	}
+
+	checkPool(c) // restarted, not out of sync
+	checkPool(c)
+}
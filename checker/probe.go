@@ -0,0 +1,297 @@
+/**
+ * @file probe.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief probes of service liveness
+ *
+ * Provide probes, which are run before checker. Service is down if probe
+ * failed. Built-in probes: tcp (connect), banner (expect regexp in first
+ * data from service after send), udp (send datagram and expect response
+ * with regexp), tls (handshake), http (GET path, expect status code) and
+ * none. Default probe is tcp for tcp services and none for udp services.
+ */
+
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Probe checks that service is alive, send and expect are probe-specific
+type Probe func(ctx context.Context, addr string, port int, send,
+	expect string) error
+
+var (
+	probes = map[string]Probe{
+		"none":   noneProbe,
+		"tcp":    tcpProbe,
+		"banner": bannerProbe,
+		"udp":    udpProbe,
+		"tls":    tlsProbe,
+		"http":   httpProbe,
+	}
+	probesMutex sync.Mutex
+
+	// Expect regexps are same for all teams, so compiled once
+	expectRegexps      = make(map[string]*regexp.Regexp)
+	expectRegexpsMutex sync.Mutex
+)
+
+// RegisterProbe add probe, which can be used by service
+func RegisterProbe(name string, p Probe) {
+
+	probesMutex.Lock()
+
+	defer probesMutex.Unlock()
+
+	probes[name] = p
+}
+
+// ProbeRegistered returns true if probe with name exist
+func ProbeRegistered(name string) bool {
+
+	probesMutex.Lock()
+
+	defer probesMutex.Unlock()
+
+	_, ok := probes[name]
+	return ok
+}
+
+// expectRegexp returns compiled expect regexp of banner and udp probes
+func expectRegexp(expect string) (re *regexp.Regexp, err error) {
+
+	expectRegexpsMutex.Lock()
+
+	defer expectRegexpsMutex.Unlock()
+
+	re, ok := expectRegexps[expect]
+	if ok {
+		return
+	}
+
+	re, err = regexp.Compile(expect)
+	if err != nil {
+		return
+	}
+
+	expectRegexps[expect] = re
+	return
+}
+
+// serviceProbe returns name of probe of service
+func serviceProbe(svc steward.Service) string {
+
+	if svc.Probe != "" {
+		return svc.Probe
+	}
+
+	if svc.UDP {
+		return "none"
+	}
+
+	return "tcp"
+}
+
+// probe service of team, returns nil if service is alive
+func probe(team steward.Team, svc steward.Service) (err error) {
+
+	name := serviceProbe(svc)
+
+	probesMutex.Lock()
+	p, ok := probes[name]
+	probesMutex.Unlock()
+
+	if !ok {
+		return errors.New("probe " + name + " is not registered")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		portCheckTimeout)
+
+	defer cancel()
+
+	err = p(ctx, team.Vulnbox, svc.Port, svc.ProbeSend, svc.ProbeExpect)
+	if err != nil {
+		err = fmt.Errorf("%s probe failed: %s", name, err)
+	}
+
+	return
+}
+
+func hostPort(addr string, port int) string {
+	return net.JoinHostPort(addr, strconv.Itoa(port))
+}
+
+func dial(ctx context.Context, network, addr string, port int) (
+	conn net.Conn, err error) {
+
+	var d net.Dialer
+
+	conn, err = d.DialContext(ctx, network, hostPort(addr, port))
+	if err != nil {
+		return
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	return
+}
+
+// match returns error if data does not match expect regexp
+func match(expect string, data []byte) (err error) {
+
+	re, err := expectRegexp(expect)
+	if err != nil {
+		return
+	}
+
+	if !re.Match(data) {
+		err = fmt.Errorf("response %q does not match %q", data, expect)
+	}
+
+	return
+}
+
+func noneProbe(ctx context.Context, addr string, port int, send,
+	expect string) error {
+
+	return nil
+}
+
+func tcpProbe(ctx context.Context, addr string, port int, send,
+	expect string) (err error) {
+
+	conn, err := dial(ctx, "tcp", addr, port)
+	if err != nil {
+		return
+	}
+
+	conn.Close()
+	return
+}
+
+func bannerProbe(ctx context.Context, addr string, port int, send,
+	expect string) (err error) {
+
+	conn, err := dial(ctx, "tcp", addr, port)
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	if send != "" {
+		_, err = io.WriteString(conn, send)
+		if err != nil {
+			return
+		}
+	}
+
+	buf := make([]byte, 4096)
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		return
+	}
+
+	return match(expect, buf[:n])
+}
+
+func udpProbe(ctx context.Context, addr string, port int, send,
+	expect string) (err error) {
+
+	conn, err := dial(ctx, "udp", addr, port)
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, send)
+	if err != nil {
+		return
+	}
+
+	buf := make([]byte, 65535)
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		return
+	}
+
+	return match(expect, buf[:n])
+}
+
+func tlsProbe(ctx context.Context, addr string, port int, send,
+	expect string) (err error) {
+
+	conn, err := dial(ctx, "tcp", addr, port)
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	// Services usually use self-signed certificates
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: addr,
+		InsecureSkipVerify: true})
+
+	return tlsConn.HandshakeContext(ctx)
+}
+
+// httpProbe do GET request of path (send, "/" by default) and expect status
+// code (any below 500 by default)
+func httpProbe(ctx context.Context, addr string, port int, send,
+	expect string) (err error) {
+
+	path := send
+	if path == "" {
+		path = "/"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		"http://"+hostPort(addr, port)+path, nil)
+	if err != nil {
+		return
+	}
+
+	client := http.Client{Timeout: portCheckTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+
+	resp.Body.Close()
+
+	if expect == "" {
+		if resp.StatusCode >= 500 {
+			err = errors.New("status " + resp.Status)
+		}
+		return
+	}
+
+	if strconv.Itoa(resp.StatusCode) != expect {
+		err = fmt.Errorf("status %s instead of %s", resp.Status, expect)
+	}
+
+	return
+}
@@ -0,0 +1,184 @@
+/**
+ * @file probe_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test probes of service liveness
+ */
+
+package checker
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+func localService(addr string) (team steward.Team, svc steward.Service) {
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		log.Fatalln("Split address failed:", err)
+	}
+
+	team.Vulnbox = host
+	svc.Port, _ = strconv.Atoi(port)
+	return
+}
+
+func TestTCPProbes(*testing.T) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalln("Listen failed:", err)
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("220 service ready\n"))
+			conn.Close()
+		}
+	}()
+
+	team, svc := localService(ln.Addr().String())
+
+	err = probe(team, svc)
+	if err != nil {
+		log.Fatalln("Default tcp probe failed:", err)
+	}
+
+	svc.Probe, svc.ProbeExpect = "banner", "^220 "
+	err = probe(team, svc)
+	if err != nil {
+		log.Fatalln("Banner probe failed:", err)
+	}
+
+	svc.ProbeExpect = "^500 "
+	if probe(team, svc) == nil {
+		log.Fatalln("Banner probe does not fail on other banner")
+	}
+
+	ln.Close()
+
+	svc.Probe = "tcp"
+	if probe(team, svc) == nil {
+		log.Fatalln("Tcp probe does not fail on closed port")
+	}
+
+	// Udp service without probe is always alive
+	svc.Probe, svc.UDP = "", true
+	err = probe(team, svc)
+	if err != nil {
+		log.Fatalln("Default udp probe failed:", err)
+	}
+}
+
+func TestUDPProbe(*testing.T) {
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalln("Listen failed:", err)
+	}
+
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			reply := strings.Replace(string(buf[:n]), "PING",
+				"PONG", 1)
+			conn.WriteTo([]byte(reply), addr)
+		}
+	}()
+
+	team, svc := localService(conn.LocalAddr().String())
+	svc.UDP, svc.Probe = true, "udp"
+	svc.ProbeSend, svc.ProbeExpect = "PING", "^PONG$"
+
+	err = probe(team, svc)
+	if err != nil {
+		log.Fatalln("Udp probe failed:", err)
+	}
+
+	svc.ProbeSend = "HELLO"
+	if probe(team, svc) == nil {
+		log.Fatalln("Udp probe does not fail on invalid response")
+	}
+}
+
+func TestHTTPProbes(*testing.T) {
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	srv := httptest.NewServer(handler)
+
+	defer srv.Close()
+
+	team, svc := localService(srv.Listener.Addr().String())
+	svc.Probe, svc.ProbeSend = "http", "/health"
+
+	err := probe(team, svc)
+	if err != nil {
+		log.Fatalln("Http probe failed:", err)
+	}
+
+	svc.ProbeExpect = "204"
+	if probe(team, svc) == nil {
+		log.Fatalln("Http probe does not fail on other status")
+	}
+
+	svc.ProbeSend, svc.ProbeExpect = "/", ""
+	if probe(team, svc) == nil {
+		log.Fatalln("Http probe does not fail on server error")
+	}
+
+	tlsSrv := httptest.NewTLSServer(handler)
+
+	defer tlsSrv.Close()
+
+	team, svc = localService(tlsSrv.Listener.Addr().String())
+	svc.Probe = "tls"
+
+	err = probe(team, svc)
+	if err != nil {
+		log.Fatalln("Tls probe failed:", err)
+	}
+
+	// Plain http server does not do handshake
+	team, svc = localService(srv.Listener.Addr().String())
+	svc.Probe = "tls"
+	if probe(team, svc) == nil {
+		log.Fatalln("Tls probe does not fail on plain service")
+	}
+}
+
+func TestUnknownProbe(*testing.T) {
+
+	if ProbeRegistered("unknown") {
+		log.Fatalln("Unknown probe is registered")
+	}
+
+	if probe(steward.Team{}, steward.Service{Probe: "unknown"}) == nil {
+		log.Fatalln("Unknown probe does not fail")
+	}
+}
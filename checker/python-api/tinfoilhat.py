@@ -1,12 +1,18 @@
 #!/usr/bin/python3
 
-from sys import stderr
+from sys import stderr, stdin
+import json
 
 STATUS_CHECKER_ERROR = 1
 STATUS_SERVICE_MUMBLE = 2
 STATUS_SERVICE_CORRUPT = 3
 STATUS_SERVICE_DOWN = 4
 
+STATUS_CODES = {"up": 0, "error": STATUS_CHECKER_ERROR,
+                "mumble": STATUS_SERVICE_MUMBLE,
+                "corrupt": STATUS_SERVICE_CORRUPT,
+                "down": STATUS_SERVICE_DOWN}
+
 class ServiceMumbleException(Exception):
     pass
 
@@ -26,54 +32,94 @@ class Checker(object):
 
     def usage(self):
         error("Usage:")
-        error("\tput HOST PORT FLAG\tПоложить флаг в сервис. Возвращает состояние.")
-        error("\tget HOST PORT STATE\tПолучить флаг из сервиса для состояния.")
+        error("\tput HOST PORT FLAG [VULN]\tПоложить флаг в сервис. Возвращает состояние.")
+        error("\tget HOST PORT STATE [VULN]\tПолучить флаг из сервиса для состояния.")
         error("\tchk HOST PORT\tПроверить доступность и целостность сервиса.")
+        error("\tworker\tОбрабатывать задания (json) со stdin.")
 
-    def __init__(self, argv):
-        if len(argv) < 3:
-            self.usage()
-            exit(STATUS_CHECKER_ERROR)
+    # Результат печатается в json (json_protocol = true в настройках
+    # сервиса), иначе состояние передается кодом возврата
+    json_protocol = False
+
+    def run(self, command, host, port, arg):
+        result = {"status": "up"}
 
         try:
-            error(argv)
-            cmd = argv[1]
-            host = argv[2]
-            port = int(argv[3])
-
-            if "put" == cmd:
-                if len(argv) < 5:
-                    error("Недостаточно аргументов.")
-                    exit(STATUS_CHECKER_ERROR)
-                flag = argv[4]
-                error('Put flag \'' + str(flag) + '\' to '
-                      + str(host) + ':' + str(port))
-                print(self.put(host, port, flag))
-
-            elif "get" == cmd:
-                if len(argv) < 5:
-                    error("Недостаточно аргументов.")
-                    exit(STATUS_CHECKER_ERROR)
-                state = argv[4]
-                error('Get flag from ' + str(host) + ':' + str(port))
-                print(self.get(host, port, state))
-
-            elif "chk" == cmd:
-                error('Check status of ' + str(host) + ':' + str(port))
+            if "put" == command:
+                cred = self.put(host, port, arg)
+                if isinstance(cred, tuple):
+                    # (cred, public flag id)
+                    cred, result["flag_id"] = cred[0], str(cred[1])
+                result["cred"] = str(cred)
+            elif "get" == command:
+                result["flag"] = str(self.get(host, port, arg))
+            elif "chk" == command:
                 self.chk(host, port)
-
             else:
-                self.usage()
-                exit(STATUS_CHECKER_ERROR)
+                result["status"] = "error"
 
         except ServiceMumbleException:
-            exit(STATUS_SERVICE_MUMBLE)
+            result["status"] = "mumble"
 
         except ServiceCorruptException:
-            exit(STATUS_SERVICE_CORRUPT)
+            result["status"] = "corrupt"
 
         except ServiceDownException:
-            exit(STATUS_SERVICE_DOWN)
+            result["status"] = "down"
+
+        except Exception as e:
+            result["status"] = "error"
+            result["private"] = repr(e)
+
+        return result
+
+    def worker(self):
+        for line in stdin:
+            job = json.loads(line)
+            self.vuln = int(job.get("vuln", 1))
+
+            result = self.run(job["command"], job["host"], int(job["port"]),
+                              job.get("arg"))
+            result["id"] = job["id"]
+
+            print(json.dumps(result), flush=True)
+
+    def __init__(self, argv):
+        if len(argv) == 2 and "worker" == argv[1]:
+            self.worker()
+            return
+
+        if len(argv) < 4 or argv[1] not in ("put", "get", "chk"):
+            self.usage()
+            exit(STATUS_CHECKER_ERROR)
+
+        error(argv)
+        cmd = argv[1]
+        host = argv[2]
+        port = int(argv[3])
+        # Номер уязвимости передается только для нескольких уязвимостей
+        self.vuln = int(argv[5]) if len(argv) > 5 else 1
+
+        arg = None
+        if "put" == cmd or "get" == cmd:
+            if len(argv) < 5:
+                error("Недостаточно аргументов.")
+                exit(STATUS_CHECKER_ERROR)
+            arg = argv[4]
+
+        error(cmd + ' ' + str(host) + ':' + str(port))
+        result = self.run(cmd, host, port, arg)
+
+        if "private" in result:
+            error(result["private"])
+
+        if self.json_protocol:
+            print(json.dumps(result))
+        elif "up" == result["status"] and "chk" != cmd:
+            # Публичный id флага передается только по json протоколу
+            print(result.get("cred", result.get("flag")))
+
+        exit(STATUS_CODES[result["status"]])
 
     """
     Положить флаг в сервис
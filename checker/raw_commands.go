@@ -5,31 +5,38 @@
  * @date September, 2015
  * @brief functions for run checkers
  *
- * Provide functions for call checker executables
+ * Provide functions for call checker executables. Checker reports result
+ * by exit code, or, if json protocol is enabled for service, by json object
+ * with status, public message (for team), private message (for jury), cred
+ * and public flag id (for put) and flag (for get) on stdout. For services
+ * with several flag stores id of vuln is passed as last argument of put and
+ * get.
  */
 
 package checker
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"os/exec"
 	"strings"
+	"syscall"
 	"time"
 
-	system "github.com/jollheef/go-system"
 	"github.com/jollheef/tin_foil_hat/steward"
 )
 
 var (
-	timeout            = "10s" // max checker work time
-	portCheckTimeout   = time.Second * 10
-	connectionAttempts = "2" // ssh option
-	connectTimeout     = "5" // ssh option
+	timeout          = time.Second * 10 // max checker work time
+	portCheckTimeout = time.Second * 10
 )
 
 // SetTimeout set max checker work time
 func SetTimeout(d time.Duration) {
 	portCheckTimeout = d
-	timeout = fmt.Sprintf("%ds", int(d.Seconds()))
+	timeout = d
 }
 
 func parseState(ret int) steward.ServiceState {
@@ -37,10 +44,9 @@ func parseState(ret int) steward.ServiceState {
 	switch ret {
 	case 0:
 		return steward.StatusUP
-	case 124: // returns by timeout
+	case 124: // killed by timeout
 		return steward.StatusDown
-	case 1:
-	case 255: // Could not resolve hostname
+	case 1, 255: // checker error
 		return steward.StatusError
 	case 2:
 		return steward.StatusMumble
@@ -53,103 +59,131 @@ func parseState(ret int) steward.ServiceState {
 	return steward.StatusUnknown
 }
 
-func put(checker, ip string, port int, flag string) (cred, logs string,
-	state steward.ServiceState, err error) {
-
-	cred, logs, ret, err := system.System("timeout", timeout, checker, "put", ip,
-		fmt.Sprintf("%d", port), flag)
+func parseStateName(name string) steward.ServiceState {
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
+	for state := steward.StatusUP; state < steward.StatusUnknown; state++ {
+		if state.String() == name {
+			return state
+		}
 	}
 
-	cred = strings.Trim(cred, " \n")
-
-	return
+	return steward.StatusUnknown
 }
 
-func sshPut(host, checker, ip string, port int, flag string) (cred, logs string,
-	state steward.ServiceState, err error) {
+// parseOutput parse checker output according to protocol
+func parseOutput(jsonProtocol bool, stdout string, ret int, runErr error) (
+	res Result, state steward.ServiceState, err error) {
+
+	if !jsonProtocol {
+		res.Cred = strings.Trim(stdout, " \n")
+		res.Flag = res.Cred
 
-	cred, logs, ret, err := system.System("ssh",
-		"-o", "ConnectTimeout="+connectTimeout,
-		"-o", "ConnectionAttempts="+connectionAttempts,
-		host, "timeout", timeout, checker,
-		"put", ip, fmt.Sprintf("%d", port), flag)
+		state = parseState(ret)
+		if state == steward.StatusUnknown {
+			err = runErr
+		}
+		return
+	}
+
+	err = json.Unmarshal([]byte(stdout), &res)
+	if err != nil {
+		// No output, e.g. checker killed by timeout
+		state = parseState(ret)
+		if state == steward.StatusUP {
+			state = steward.StatusError
+		}
+
+		if state != steward.StatusUnknown {
+			err = nil
+		}
+		return
+	}
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
+	state = parseStateName(res.Status)
+	if state == steward.StatusUnknown {
+		err = fmt.Errorf("unknown status '%s'", res.Status)
+		return
 	}
 
-	cred = strings.Trim(cred, " \n")
+	res.Cred = strings.Trim(res.Cred, " \n")
+	res.FlagID = strings.Trim(res.FlagID, " \n")
+	res.Flag = strings.Trim(res.Flag, " \n")
 
 	return
 }
 
-func get(checker, ip string, port int, cred string) (flag, logs string,
-	state steward.ServiceState, err error) {
+// ExecChecker runs checker executable
+type ExecChecker struct {
+	Path         string
+	JSONProtocol bool
+	Vulns        int // vuln is passed to checker only if more than one
+}
 
-	flag, logs, ret, err := system.System("timeout", timeout, checker, "get", ip,
-		fmt.Sprintf("%d", port), cred)
+func (c ExecChecker) args(command, addr string, port, vuln int,
+	arg string) (args []string) {
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
+	args = []string{command, addr, fmt.Sprintf("%d", port), arg}
+	if c.Vulns > 1 {
+		args = append(args, fmt.Sprintf("%d", vuln))
 	}
-
-	flag = strings.Trim(flag, " \n")
-
 	return
 }
 
-func sshGet(host, checker, ip string, port int, cred string) (flag, logs string,
-	state steward.ServiceState, err error) {
+// Put flag to service
+func (c ExecChecker) Put(ctx context.Context, addr string, port, vuln int,
+	flag string) (res Result, state steward.ServiceState, err error) {
 
-	flag, logs, ret, err := system.System("ssh",
-		"-o", "ConnectTimeout="+connectTimeout,
-		"-o", "ConnectionAttempts="+connectionAttempts,
-		host, "timeout", timeout, checker,
-		"get", ip, fmt.Sprintf("%d", port), cred)
+	return c.run(ctx, c.args("put", addr, port, vuln, flag)...)
+}
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
-	}
+// Get flag from service
+func (c ExecChecker) Get(ctx context.Context, addr string, port, vuln int,
+	cred string) (res Result, state steward.ServiceState, err error) {
 
-	flag = strings.Trim(flag, " \n")
+	return c.run(ctx, c.args("get", addr, port, vuln, cred)...)
+}
 
-	return
+// Check service logic
+func (c ExecChecker) Check(ctx context.Context, addr string, port int) (
+	res Result, state steward.ServiceState, err error) {
+
+	return c.run(ctx, "chk", addr, fmt.Sprintf("%d", port))
 }
 
-func check(checker, ip string, port int) (state steward.ServiceState,
-	logs string, err error) {
+func (c ExecChecker) run(ctx context.Context, args ...string) (res Result,
+	state steward.ServiceState, err error) {
 
-	_, logs, ret, err := system.System("timeout", timeout, checker, "chk", ip,
-		fmt.Sprintf("%d", port))
+	command := strings.Join(append([]string{c.Path}, args...), " ")
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
-	}
+	var stdout, stderr bytes.Buffer
 
-	return
-}
+	cmd := exec.CommandContext(ctx, c.Path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
-func sshCheck(host, checker, ip string, port int) (state steward.ServiceState,
-	logs string, err error) {
+	// Kill checker with all children
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
 
-	_, logs, ret, err := system.System("ssh",
-		"-o", "ConnectTimeout="+connectTimeout,
-		"-o", "ConnectionAttempts="+connectionAttempts,
-		host, "timeout", timeout, checker,
-		"chk", ip, fmt.Sprintf("%d", port))
+	runErr := cmd.Run()
 
-	state = parseState(ret)
-	if state != steward.StatusUnknown {
-		err = nil
+	ret := 0
+	if ctx.Err() == context.DeadlineExceeded {
+		ret = 124 // as timeout(1)
+	} else if exitErr, ok := runErr.(*exec.ExitError); ok {
+		ret = exitErr.ExitCode()
+	} else if runErr != nil {
+		ret = -1
 	}
 
+	res, state, err = parseOutput(c.JSONProtocol, stdout.String(),
+		ret, runErr)
+
+	res.Command = command
+	res.Stdout, res.Stderr, res.ExitCode = stdout.String(),
+		stderr.String(), ret
+
 	return
 }
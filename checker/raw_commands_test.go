@@ -0,0 +1,272 @@
+/**
+ * @file raw_commands_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test checker executables
+ */
+
+package checker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+	"github.com/jollheef/tin_foil_hat/vexillary"
+)
+
+func TestParseOutput(*testing.T) {
+
+	// Exit code protocol
+	res, state, err := parseOutput(false, "cred\n", 2, nil)
+	if err != nil || state != steward.StatusMumble || res.Cred != "cred" {
+		log.Fatalln("Invalid exit code result:", res, state, err)
+	}
+
+	stdout := `{"status": "mumble", "public": "Cannot login",
+		"private": "HTTP 500 on /login", "cred": "id:42",
+		"flag_id": "user42\n"}`
+
+	res, state, err = parseOutput(true, stdout, 0, nil)
+	if err != nil || state != steward.StatusMumble {
+		log.Fatalln("Invalid json result:", state, err)
+	}
+
+	if res.Public != "Cannot login" || res.Cred != "id:42" ||
+		res.Private != "HTTP 500 on /login" || res.FlagID != "user42" {
+		log.Fatalln("Invalid json result:", res)
+	}
+
+	// Killed by timeout
+	_, state, err = parseOutput(true, "", 124, nil)
+	if err != nil || state != steward.StatusDown {
+		log.Fatalln("Invalid timeout result:", state, err)
+	}
+
+	// Exit code is ok, but output is broken
+	_, state, err = parseOutput(true, "not json", 0, nil)
+	if err != nil || state != steward.StatusError {
+		log.Fatalln("Invalid broken output result:", state, err)
+	}
+
+	// Exception in checker, e.g. traceback of python api
+	_, state, err = parseOutput(true, "Traceback", 1,
+		errors.New("exit status 1"))
+	if err != nil || state != steward.StatusError {
+		log.Fatalln("Invalid checker error result:", state, err)
+	}
+
+	_, state, err = parseOutput(true, `{"status": "lol"}`, 0, nil)
+	if err == nil {
+		log.Fatalln("Unknown status accepted:", state)
+	}
+}
+
+func TestReport(*testing.T) {
+
+	res := Result{Command: "checker.py chk 127.0.0.1 80", ExitCode: 2,
+		Stdout: strings.Repeat("A", logsLimit*2), Stderr: "Traceback"}
+
+	report := res.report()
+
+	if !strings.Contains(report, "[truncated]") ||
+		!strings.Contains(report, "Traceback") ||
+		len(report) > logsLimit+1024 {
+		log.Fatalln("Invalid report:", report[:100])
+	}
+}
+
+func TestReportInvalidText(*testing.T) {
+
+	// Output cut inside of multi-byte rune, with NUL bytes
+	res := Result{Command: "checker.py chk 127.0.0.1 80",
+		Stdout: "\x00" + strings.Repeat("б", logsLimit),
+		Stderr: "\xff\xfe"}
+
+	report := res.report()
+
+	if !utf8.ValidString(report) || strings.Contains(report, "\x00") {
+		log.Fatalln("Invalid text in report")
+	}
+
+	var runs []Result
+	for i := 0; i < 20; i++ {
+		runs = append(runs, res)
+	}
+
+	logs := reports(nil, runs...)
+
+	if !utf8.ValidString(logs) || len(logs) > totalLogsLimit+1024 {
+		log.Fatalln("Invalid logs size:", len(logs))
+	}
+}
+
+func writeScript(text string) (path string) {
+
+	f, err := ioutil.TempFile("", "checker")
+	if err != nil {
+		log.Fatalln("Create script failed:", err)
+	}
+
+	defer f.Close()
+
+	f.WriteString("#!/bin/sh\n" + text + "\n")
+	f.Chmod(0700)
+
+	return f.Name()
+}
+
+func TestExecChecker(*testing.T) {
+
+	path := writeScript(`echo "$1 $3"; echo trace >&2; exit 2`)
+
+	defer os.Remove(path)
+
+	c := ExecChecker{Path: path}
+
+	res, state, err := c.Check(context.Background(), "127.0.0.1", 80)
+	if err != nil || state != steward.StatusMumble {
+		log.Fatalln("Invalid check result:", state, err)
+	}
+
+	if res.Stdout != "chk 80\n" || res.Stderr != "trace\n" ||
+		res.ExitCode != 2 {
+		log.Fatalln("Invalid check output:", res)
+	}
+
+	path = writeScript(`echo '{"status": "up", "cred": "'$4:$5'"}'`)
+
+	defer os.Remove(path)
+
+	c = ExecChecker{Path: path, JSONProtocol: true, Vulns: 2}
+
+	res, state, err = c.Put(context.Background(), "127.0.0.1", 80, 2,
+		"FLAG")
+	if err != nil || state != steward.StatusUP || res.Cred != "FLAG:2" {
+		log.Fatalln("Invalid json put result:", res, state, err)
+	}
+}
+
+func TestExecCheckerPythonAPI(*testing.T) {
+
+	api, err := filepath.Abs("python-api")
+	if err != nil {
+		log.Fatalln("Get path of python api failed:", err)
+	}
+
+	path := writeScript(`exec python3 - "$@" <<EOF
+import sys
+sys.path.insert(0, "` + api + `")
+from tinfoilhat import Checker, ServiceMumbleException
+
+class TestChecker(Checker):
+    json_protocol = True
+
+    def put(self, host, port, flag):
+        return (flag[::-1], "id" + str(self.vuln))
+
+    def get(self, host, port, cred):
+        return cred[::-1]
+
+    def chk(self, host, port):
+        if port != 80:
+            raise ServiceMumbleException()
+
+TestChecker(sys.argv)
+EOF`)
+
+	defer os.Remove(path)
+
+	c := ExecChecker{Path: path, JSONProtocol: true, Vulns: 2}
+
+	ctx := context.Background()
+
+	res, state, err := c.Put(ctx, "127.0.0.1", 80, 2, "FLAG")
+	if err != nil || state != steward.StatusUP || res.Cred != "GALF" ||
+		res.FlagID != "id2" {
+		log.Fatalln("Invalid python put result:", res, state, err)
+	}
+
+	res, state, err = c.Get(ctx, "127.0.0.1", 80, 2, res.Cred)
+	if err != nil || state != steward.StatusUP || res.Flag != "FLAG" {
+		log.Fatalln("Invalid python get result:", res, state, err)
+	}
+
+	_, state, err = c.Check(ctx, "127.0.0.1", 80)
+	if err != nil || state != steward.StatusUP {
+		log.Fatalln("Invalid python check result:", state, err)
+	}
+
+	_, state, err = c.Check(ctx, "127.0.0.1", 81)
+	if err != nil || state != steward.StatusMumble {
+		log.Fatalln("Invalid python mumble result:", state, err)
+	}
+}
+
+func TestExecCheckerTimeout(*testing.T) {
+
+	path := writeScript("sleep 10")
+
+	defer os.Remove(path)
+
+	c := ExecChecker{Path: path}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		100*time.Millisecond)
+
+	defer cancel()
+
+	start := time.Now()
+
+	_, state, err := c.Check(ctx, "127.0.0.1", 80)
+	if err != nil || state != steward.StatusDown {
+		log.Fatalln("Invalid timeout result:", state, err)
+	}
+
+	if time.Since(start) > time.Second {
+		log.Fatalln("Checker is not killed by timeout")
+	}
+}
+
+func TestSelfTest(*testing.T) {
+
+	key, err := vexillary.GenerateKey()
+	if err != nil {
+		log.Fatalln("Generate key failed:", err)
+	}
+
+	// Cred is flag itself
+	path := writeScript(`[ "$1" = "chk" ] && exit $CHK; echo $4`)
+
+	defer os.Remove(path)
+
+	team := steward.Team{Vulnbox: "127.0.0.1"}
+	svc := steward.Service{CheckerPath: path, Probe: "none", Vulns: 2}
+
+	os.Setenv("CHK", "0")
+
+	status, err := SelfTest(key, team, svc)
+	if err != nil || status.State != steward.StatusUP {
+		log.Fatalln("Invalid self test result:", status, err)
+	}
+
+	if strings.Count(status.Logs, "$ "+path) != 5 {
+		log.Fatalln("Invalid self test logs:", status.Logs)
+	}
+
+	os.Setenv("CHK", "2")
+
+	status, err = SelfTest(key, team, svc)
+	if err != nil || status.State != steward.StatusMumble {
+		log.Fatalln("Invalid failed self test result:", status, err)
+	}
+}
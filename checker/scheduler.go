@@ -0,0 +1,161 @@
+/**
+ * @file scheduler.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief schedule checker runs
+ *
+ * Provide limits of parallel checker runs (in total, per service and per
+ * netbox), random order of checks and random delay before start of each
+ * check, so teams can't tell checker traffic by its timing.
+ */
+
+package checker
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// semaphore limits parallel runs, nil semaphore means unlimited
+type semaphore chan struct{}
+
+func newSemaphore(n int) semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(semaphore, n)
+}
+
+func (s semaphore) acquire() {
+	if s != nil {
+		s <- struct{}{}
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+var (
+	totalLimit   semaphore
+	serviceLimit int
+	netboxLimit  int
+	jitter       time.Duration // max delay before start of check
+
+	semaphores      = make(map[string]semaphore) // { key : semaphore }
+	semaphoresMutex sync.Mutex
+)
+
+// SetLimits set max parallel checker runs in total, per service and per
+// netbox, zero means unlimited
+func SetLimits(total, perService, perNetbox int) {
+
+	semaphoresMutex.Lock()
+
+	defer semaphoresMutex.Unlock()
+
+	totalLimit = newSemaphore(total)
+	serviceLimit = perService
+	netboxLimit = perNetbox
+	semaphores = make(map[string]semaphore)
+}
+
+// SetJitter set max random delay before start of each check, it should be
+// less than time between checks
+func SetJitter(d time.Duration) {
+	jitter = d
+}
+
+func getSemaphore(key string, n int) (s semaphore) {
+
+	semaphoresMutex.Lock()
+
+	defer semaphoresMutex.Unlock()
+
+	s, ok := semaphores[key]
+	if !ok {
+		s = newSemaphore(n)
+		semaphores[key] = s
+	}
+
+	return
+}
+
+// limits returns semaphores for run checker of service for team
+func limits(team steward.Team, svc steward.Service) (sems []semaphore) {
+
+	if team.UseNetbox {
+		sems = append(sems, getSemaphore("netbox "+team.Netbox,
+			netboxLimit))
+	}
+
+	sems = append(sems, getSemaphore(fmt.Sprintf("service %d", svc.ID),
+		serviceLimit))
+
+	// Global limit is acquired last, so waiting for others limits
+	// does not hold global slot
+	semaphoresMutex.Lock()
+	sems = append(sems, totalLimit)
+	semaphoresMutex.Unlock()
+
+	return
+}
+
+// schedule call fn for each team and service in random order with random
+// delay and respect of limits, returns after all calls
+func schedule(teams []steward.Team, services []steward.Service,
+	fn func(team steward.Team, svc steward.Service)) {
+
+	type task struct {
+		team steward.Team
+		svc  steward.Service
+	}
+
+	var tasks []task
+
+	for _, team := range teams {
+		for _, svc := range services {
+			tasks = append(tasks, task{team, svc})
+		}
+	}
+
+	rand.Shuffle(len(tasks), func(i, j int) {
+		tasks[i], tasks[j] = tasks[j], tasks[i]
+	})
+
+	var wg sync.WaitGroup
+
+	for _, t := range tasks {
+
+		wg.Add(1)
+
+		go func(t task) {
+			defer wg.Done()
+
+			if jitter > 0 {
+				time.Sleep(time.Duration(rand.Int63n(int64(jitter))))
+			}
+
+			sems := limits(t.team, t.svc)
+
+			for _, s := range sems {
+				s.acquire()
+			}
+
+			fn(t.team, t.svc)
+
+			for _, s := range sems {
+				s.release()
+			}
+		}(t)
+	}
+
+	wg.Wait()
+}
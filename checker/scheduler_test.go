@@ -0,0 +1,130 @@
+/**
+ * @file scheduler_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test schedule of checker runs
+ */
+
+package checker
+
+import (
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+type counter struct {
+	mutex   sync.Mutex
+	current map[string]int
+	max     map[string]int
+}
+
+func (c *counter) add(key string, delta int) {
+
+	c.mutex.Lock()
+
+	defer c.mutex.Unlock()
+
+	c.current[key] += delta
+	if c.current[key] > c.max[key] {
+		c.max[key] = c.current[key]
+	}
+}
+
+func TestSchedule(*testing.T) {
+
+	SetLimits(4, 2, 1)
+
+	defer SetLimits(0, 0, 0)
+
+	var teams []steward.Team
+	var services []steward.Service
+
+	for i := 1; i <= 10; i++ {
+		team := steward.Team{ID: i}
+		if i <= 5 {
+			team.UseNetbox = true
+			team.Netbox = "netbox"
+		}
+		teams = append(teams, team)
+	}
+
+	for i := 1; i <= 3; i++ {
+		services = append(services, steward.Service{ID: i})
+	}
+
+	c := counter{current: make(map[string]int),
+		max: make(map[string]int)}
+
+	calls := 0
+
+	schedule(teams, services, func(team steward.Team,
+		svc steward.Service) {
+
+		keys := []string{"total", string(rune('0' + svc.ID))}
+		if team.UseNetbox {
+			keys = append(keys, "netbox")
+		}
+
+		for _, key := range keys {
+			c.add(key, 1)
+		}
+
+		time.Sleep(time.Millisecond)
+
+		c.mutex.Lock()
+		calls++
+		c.mutex.Unlock()
+
+		for _, key := range keys {
+			c.add(key, -1)
+		}
+	})
+
+	if calls != len(teams)*len(services) {
+		log.Fatalln("Scheduled", calls, "calls instead",
+			len(teams)*len(services))
+	}
+
+	if c.max["total"] > 4 || c.max["1"] > 2 || c.max["netbox"] > 1 {
+		log.Fatalln("Limits exceeded:", c.max)
+	}
+}
+
+func TestScheduleJitter(*testing.T) {
+
+	SetJitter(100 * time.Millisecond)
+
+	defer SetJitter(0)
+
+	var starts []time.Time
+	var mutex sync.Mutex
+
+	teams := make([]steward.Team, 20)
+	services := []steward.Service{{ID: 1}}
+
+	schedule(teams, services, func(team steward.Team,
+		svc steward.Service) {
+		mutex.Lock()
+		starts = append(starts, time.Now())
+		mutex.Unlock()
+	})
+
+	first, last := starts[0], starts[0]
+	for _, start := range starts {
+		if start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+
+	if last.Sub(first) < 10*time.Millisecond {
+		log.Fatalln("Checks are not spread in time")
+	}
+}
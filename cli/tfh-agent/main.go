@@ -0,0 +1,83 @@
+/**
+ * @file main.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief checker agent for netboxes
+ *
+ * Entry point for agent, which runs checkers on netbox by jobs from jury
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/jollheef/tin_foil_hat/checker"
+)
+
+var (
+	listen = kingpin.Flag("listen", "Address for jury connections.").
+		Default(":9100").String()
+
+	caFile = kingpin.Flag("ca",
+		"CA certificate, signs certificates of jury and agents.").
+		Required().String()
+
+	certFile = kingpin.Flag("cert",
+		"Certificate of agent, issued for name tfh-agent.").
+		Required().String()
+
+	keyFile = kingpin.Flag("key", "Key of agent.").Required().String()
+
+	checkersDir = kingpin.Flag("checkers-dir",
+		"Directory with checkers, named as services.").
+		Required().String()
+)
+
+var (
+	commitID  string
+	buildDate string
+	buildTime string
+)
+
+func buildInfo() (str string) {
+
+	if len(commitID) > 7 {
+		commitID = commitID[:7] // abbreviated commit hash
+	}
+
+	str = fmt.Sprintf("Version: tin_foil_hat %s %s %s\n",
+		commitID, buildDate, buildTime)
+	str += "Author: Mikhail Klementyev <jollheef@riseup.net>\n"
+	return
+}
+
+func main() {
+
+	fmt.Println(buildInfo())
+
+	kingpin.Parse()
+
+	config, err := checker.AgentTLSConfig(*caFile, *certFile, *keyFile)
+	if err != nil {
+		log.Fatalln("Load certificates failed:", err)
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalln("Listen failed:", err)
+	}
+
+	log.Println("Agent listen on", *listen, "with checkers from",
+		*checkersDir)
+
+	err = checker.ServeAgent(ln, config, *checkersDir)
+	if err != nil {
+		log.Fatalln("Serve failed:", err)
+	}
+}
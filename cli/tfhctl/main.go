@@ -11,17 +11,26 @@
 package main
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/olekukonko/tablewriter"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/jollheef/tin_foil_hat/anticheat"
+	"github.com/jollheef/tin_foil_hat/checker"
+	"github.com/jollheef/tin_foil_hat/checker/builtin"
 	"github.com/jollheef/tin_foil_hat/config"
 	"github.com/jollheef/tin_foil_hat/scoreboard"
 	"github.com/jollheef/tin_foil_hat/steward"
+	"github.com/jollheef/tin_foil_hat/vexillary"
 )
 
 var (
@@ -45,6 +54,52 @@ var (
 
 	advUnhide   = adv.Command("unhide", "Unhide advisory.")
 	advUnhideID = advUnhide.Arg("id", "advisory id").Required().Int()
+
+	checks        = kingpin.Command("checks", "View checker logs.")
+	checksTeam    = checks.Flag("team", "Team id.").Int()
+	checksService = checks.Flag("service", "Service id.").Int()
+	checksRound   = checks.Flag("round", "Round.").Int()
+
+	submissions = kingpin.Command("submissions",
+		"View flag submission stats.")
+	submissionsTeam = submissions.Flag("team",
+		"Team id, show all submissions of team.").Int()
+
+	anticheatCmd    = kingpin.Command("anticheat", "Detect cheating.")
+	anticheatReport = anticheatCmd.Command("report",
+		"Report suspicious patterns in submissions.")
+	anticheatWindow = anticheatReport.Flag("window",
+		"Max time between submits of same flag by "+
+			"two teams.").Default("10s").Duration()
+	anticheatMinFlags = anticheatReport.Flag("min-flags",
+		"Min count of same flags of two teams.").Default("5").Int()
+	anticheatMinRatio = anticheatReport.Flag("min-ratio",
+		"Min part of same flags in captures.").Default("0.5").Float64()
+
+	anticheatImport = anticheatCmd.Command("import",
+		"Import connections of teams from traffic records.")
+	anticheatImportFile = anticheatImport.Arg("file",
+		"Records 'src_ip dst_ip dst_port', one per line.").
+		Required().ExistingFile()
+
+	keyCmd    = kingpin.Command("key", "Work with flag keys.")
+	keyList   = keyCmd.Command("list", "List keys, last key signs new flags.")
+	keyRotate = keyCmd.Command("rotate",
+		"Add new key for sign flags, old keys stay valid.")
+	keyCheck     = keyCmd.Command("check", "Check flag by keys.")
+	keyCheckFlag = keyCheck.Arg("flag", "Flag.").Required().String()
+
+	checkerCmd  = kingpin.Command("checker", "Work with checkers.")
+	checkerTest = checkerCmd.Command("test",
+		"Run put, check and get of service on reference vulnbox.")
+	checkerTestService = checkerTest.Flag("service",
+		"Service name.").Required().String()
+	checkerTestHost = checkerTest.Flag("host",
+		"Address of reference vulnbox.").Required().String()
+	checkerTestNetbox = checkerTest.Flag("netbox",
+		"Run checker on netbox by agent.").String()
+	checkerTestCount = checkerTest.Flag("count",
+		"Count of runs.").Default("3").Int()
 )
 
 var (
@@ -109,6 +164,357 @@ func advisoryUnhide(db *sql.DB) {
 	}
 }
 
+func checksShow(db *sql.DB) {
+	statuses, err := steward.GetStatuses(db, *checksRound, *checksTeam,
+		*checksService)
+	if err != nil {
+		log.Fatalln("Get statuses fail:", err)
+	}
+
+	for _, status := range statuses {
+
+		fmt.Printf(">>> Round %d, team %d, service %d: %s <<<\n",
+			status.Round, status.TeamID, status.ServiceID,
+			status.State)
+		fmt.Printf("(Exit code: %d, Duration: %s, Timestamp: %s)\n",
+			status.ExitCode, status.Duration,
+			status.Timestamp.String())
+
+		if status.Message != "" {
+			fmt.Println("Message:", status.Message)
+		}
+
+		fmt.Println(status.Logs)
+	}
+}
+
+const topRejections = 3
+
+type teamSubmissions struct {
+	total    int
+	accepted int
+	first    time.Time
+	last     time.Time
+	rejects  []steward.SubmissionStat
+}
+
+func submissionsList(db *sql.DB) {
+	subs, err := steward.GetSubmissions(db, *submissionsTeam)
+	if err != nil {
+		log.Fatalln("Get submissions fail:", err)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Timestamp", "Address", "Flag",
+		"Verdict"})
+
+	for _, s := range subs {
+		table.Append([]string{fmt.Sprintf("%d", s.ID),
+			s.Timestamp.String(), s.Addr, s.Flag, s.Verdict})
+	}
+
+	table.Render()
+}
+
+func submissionsShow(db *sql.DB) {
+
+	if *submissionsTeam != 0 {
+		submissionsList(db)
+		return
+	}
+
+	stats, err := steward.GetSubmissionStats(db)
+	if err != nil {
+		log.Fatalln("Get submission stats fail:", err)
+	}
+
+	teams := make(map[int]*teamSubmissions)
+	var ids []int
+
+	for _, stat := range stats {
+		ts, ok := teams[stat.TeamID]
+		if !ok {
+			ts = &teamSubmissions{first: stat.First, last: stat.Last}
+			teams[stat.TeamID] = ts
+			ids = append(ids, stat.TeamID)
+		}
+
+		ts.total += stat.Count
+		if stat.Accepted {
+			ts.accepted += stat.Count
+		} else {
+			ts.rejects = append(ts.rejects, stat)
+		}
+
+		if stat.First.Before(ts.first) {
+			ts.first = stat.First
+		}
+		if stat.Last.After(ts.last) {
+			ts.last = stat.Last
+		}
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Team", "Total", "Accepted", "Rate",
+		"Per minute", "Top rejections"})
+
+	for _, id := range ids {
+		ts := teams[id]
+
+		name := "unknown"
+		if id != 0 {
+			team, err := steward.GetTeam(db, id)
+			if err != nil {
+				log.Fatalln("Get team fail:", err)
+			}
+			name = team.Name
+		}
+
+		// Less than minute of submissions counts as one minute
+		minutes := ts.last.Sub(ts.first).Minutes()
+		if minutes < 1 {
+			minutes = 1
+		}
+
+		sort.Slice(ts.rejects, func(i, j int) bool {
+			return ts.rejects[i].Count > ts.rejects[j].Count
+		})
+
+		var rejects []string
+		for i := 0; i < len(ts.rejects) && i < topRejections; i++ {
+			rejects = append(rejects, fmt.Sprintf("%s (%d)",
+				ts.rejects[i].Verdict, ts.rejects[i].Count))
+		}
+
+		table.Append([]string{name, fmt.Sprintf("%d", ts.total),
+			fmt.Sprintf("%d", ts.accepted),
+			fmt.Sprintf("%.1f%%",
+				100*float64(ts.accepted)/float64(ts.total)),
+			fmt.Sprintf("%.2f", float64(ts.total)/minutes),
+			strings.Join(rejects, ", ")})
+	}
+
+	table.Render()
+}
+
+func anticheatReportShow(db *sql.DB) {
+
+	anticheat.SetSharing(*anticheatWindow, *anticheatMinFlags,
+		*anticheatMinRatio)
+
+	suspicions, err := anticheat.Report(db)
+	if err != nil {
+		log.Fatalln("Anticheat report fail:", err)
+	}
+
+	teams, err := steward.GetTeams(db)
+	if err != nil {
+		log.Fatalln("Get teams fail:", err)
+	}
+
+	names := make(map[int]string)
+	for _, team := range teams {
+		names[team.ID] = team.Name
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Kind", "Teams", "Count", "Details"})
+
+	for _, sus := range suspicions {
+
+		var susTeams []string
+		for _, id := range sus.Teams {
+			susTeams = append(susTeams, names[id])
+		}
+
+		table.Append([]string{sus.Kind, strings.Join(susTeams, ", "),
+			fmt.Sprintf("%d", sus.Count), sus.Details})
+	}
+
+	table.Render()
+}
+
+func anticheatImportRun(db *sql.DB) {
+
+	f, err := os.Open(*anticheatImportFile)
+	if err != nil {
+		log.Fatalln("Open records fail:", err)
+	}
+
+	defer f.Close()
+
+	imported, skipped, err := anticheat.ImportConnections(db, f)
+	if err != nil {
+		log.Fatalln("Import connections fail:", err)
+	}
+
+	fmt.Println("Imported", imported, "connections, skipped", skipped,
+		"records")
+}
+
+func keyListShow(db *sql.DB) {
+	keys, err := steward.GetFlagKeys(db)
+	if err != nil {
+		log.Fatalln("Get keys fail:", err)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Fingerprint", "Timestamp"})
+
+	for _, key := range keys {
+
+		// Key itself is secret, so only hash is shown
+		sum := sha256.Sum256(key.Key)
+
+		table.Append([]string{fmt.Sprintf("%d", key.ID),
+			fmt.Sprintf("%x", sum[:8]), key.Timestamp.String()})
+	}
+
+	table.Render()
+}
+
+func keyRotateRun(db *sql.DB) {
+	key, err := vexillary.GenerateKey()
+	if err != nil {
+		log.Fatalln("Generate key fail:", err)
+	}
+
+	id, err := steward.AddFlagKey(db, key)
+	if err != nil {
+		log.Fatalln("Add key fail:", err)
+	}
+
+	fmt.Println("Key", id, "will sign flags from next round")
+}
+
+// useFlagFormat apply flag format of game, flags are generated and
+// parsed by it
+func useFlagFormat(cfg config.Config) {
+
+	if cfg.FlagReceiver.FlagFormat == "" {
+		return
+	}
+
+	err := vexillary.SetFormat(cfg.FlagReceiver.FlagFormat)
+	if err != nil {
+		log.Fatalln("Invalid flag format:", err)
+	}
+}
+
+// signedBy returns key which signs flag
+func signedBy(flag string, keys []steward.FlagKey) (key steward.FlagKey,
+	info vexillary.Info, err error) {
+
+	for _, key = range keys {
+		info, err = vexillary.ParseFlag(flag, key.Key)
+		if err == nil {
+			return
+		}
+	}
+
+	err = errors.New("flag is not signed by any key")
+	return
+}
+
+func keyCheckRun(db *sql.DB, cfg config.Config) {
+	keys, err := steward.GetFlagKeys(db)
+	if err != nil {
+		log.Fatalln("Get keys fail:", err)
+	}
+
+	useFlagFormat(cfg)
+
+	key, info, err := signedBy(*keyCheckFlag, keys)
+	if err != nil {
+		fmt.Println("Flag is not signed by any key")
+		os.Exit(1)
+	}
+
+	fmt.Printf("Signed by key %d: round %d, team %d, service %d\n",
+		key.ID, info.Round, info.TeamID, info.ServiceID)
+}
+
+func checkerTestRun(cfg config.Config) {
+
+	var svc steward.Service
+	found := false
+
+	for _, s := range cfg.Services {
+		if s.Name == *checkerTestService {
+			svc, found = s, true
+		}
+	}
+
+	if !found {
+		log.Fatalln("Service", *checkerTestService, "not found")
+	}
+
+	team := steward.Team{Name: "reference", Vulnbox: *checkerTestHost}
+	if *checkerTestNetbox != "" {
+		team.UseNetbox, team.Netbox = true, *checkerTestNetbox
+	}
+
+	// Same checkers and flags as in game
+	builtin.Register()
+
+	err := checker.ValidService(svc)
+	if err != nil {
+		log.Fatalln("Invalid service:", err)
+	}
+
+	useFlagFormat(cfg)
+
+	checker.SetTimeout(cfg.CheckerTimeout.Duration)
+
+	if team.UseNetbox {
+		agentTLS, err := checker.JuryTLSConfig(cfg.Agent.CA,
+			cfg.Agent.Cert, cfg.Agent.Key)
+		if err != nil {
+			log.Fatalln("Load agent certificates fail:", err)
+		}
+
+		checker.SetAgent(cfg.Agent.Port, agentTLS)
+	}
+
+	key, err := vexillary.GenerateKey()
+	if err != nil {
+		log.Fatalln("Generate key fail:", err)
+	}
+
+	failed := 0
+
+	for i := 1; i <= *checkerTestCount; i++ {
+
+		status, err := checker.SelfTest(key, team, svc)
+		if err != nil {
+			log.Fatalln("Checker test fail:", err)
+		}
+
+		fmt.Printf(">>> Run %d, service %s: %s <<<\n", i, svc.Name,
+			status.State)
+		fmt.Printf("(Exit code: %d, Duration: %s)\n",
+			status.ExitCode, status.Duration)
+
+		if status.Message != "" {
+			fmt.Println("Message:", status.Message)
+		}
+
+		fmt.Println(status.Logs)
+
+		if status.State != steward.StatusUP {
+			failed++
+		}
+	}
+
+	if failed != 0 {
+		fmt.Printf("Service is not up in %d of %d runs\n", failed,
+			*checkerTestCount)
+		os.Exit(1)
+	}
+
+	fmt.Println("Service is up in all runs")
+}
+
 func scoreboardShow(db *sql.DB) {
 	res, err := scoreboard.CollectLastResult(db)
 	if err != nil {
@@ -153,6 +559,12 @@ func main() {
 		log.Fatalln("Cannot open config:", err)
 	}
 
+	// Database is not required for checker test
+	if kingpin.Parse() == "checker test" {
+		checkerTestRun(config)
+		return
+	}
+
 	db, err := steward.OpenDatabase(config.Database.Connection)
 	if err != nil {
 		log.Fatalln("Open database fail:", err)
@@ -175,6 +587,27 @@ func main() {
 	case "advisory unhide":
 		advisoryUnhide(db)
 
+	case "checks":
+		checksShow(db)
+
+	case "submissions":
+		submissionsShow(db)
+
+	case "anticheat report":
+		anticheatReportShow(db)
+
+	case "anticheat import":
+		anticheatImportRun(db)
+
+	case "key list":
+		keyListShow(db)
+
+	case "key rotate":
+		keyRotateRun(db)
+
+	case "key check":
+		keyCheckRun(db, config)
+
 	case "scoreboard":
 		scoreboardShow(db)
 	}
@@ -0,0 +1,54 @@
+/**
+ * @file main_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test control utility
+ */
+
+package main
+
+import (
+	"log"
+	"testing"
+
+	"github.com/jollheef/tin_foil_hat/config"
+	"github.com/jollheef/tin_foil_hat/steward"
+	"github.com/jollheef/tin_foil_hat/vexillary"
+)
+
+func TestSignedByFormat(*testing.T) {
+
+	var cfg config.Config
+	cfg.FlagReceiver.FlagFormat = "[A-Z0-9]{31}="
+
+	useFlagFormat(cfg)
+
+	defer vexillary.SetFormat(vexillary.DefaultFormat)
+
+	var keys []steward.FlagKey
+	for id := 1; id <= 2; id++ {
+		key, err := vexillary.GenerateKey()
+		if err != nil {
+			log.Fatalln("Generate key failed:", err)
+		}
+
+		keys = append(keys, steward.FlagKey{ID: id, Key: key})
+	}
+
+	flag, err := vexillary.GenerateFlag(keys[1].Key, 3, 5, 7)
+	if err != nil {
+		log.Fatalln("Generate flag failed:", err)
+	}
+
+	key, info, err := signedBy(flag, keys)
+	if err != nil || key.ID != 2 || info.Round != 3 || info.TeamID != 5 ||
+		info.ServiceID != 7 {
+		log.Fatalln("Invalid flag check:", flag, key.ID, info, err)
+	}
+
+	_, _, err = signedBy("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", keys)
+	if err == nil {
+		log.Fatalln("Flag with invalid signature is signed")
+	}
+}
@@ -19,22 +19,58 @@ import (
 	"github.com/naoina/toml"
 )
 
-import "github.com/jollheef/tin_foil_hat/steward"
+import (
+	"github.com/jollheef/tin_foil_hat/schedule"
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Period config, periods follow one by one from start of game
+type Period struct {
+	Name   string
+	Length Duration
+	Paused bool // e.g. lunch
+}
 
 // Pulse config
 type Pulse struct {
-	Start        Time
+	Start Time
+	// Used only without periods, as half, lunch, half
 	Half         Duration
 	Lunch        Duration
+	Periods      []Period
 	RoundLen     Duration
 	CheckTimeout Duration
 	DarkestTime  Duration
 }
 
+// Schedule returns game schedule
+func (p Pulse) Schedule() (sched schedule.Schedule) {
+
+	sched = schedule.New(p.Start.Time)
+
+	if len(p.Periods) == 0 {
+		sched.Add("first half", p.Half.Duration, true)
+		if p.Lunch.Duration != 0 {
+			sched.Add("lunch", p.Lunch.Duration, false)
+		}
+		sched.Add("second half", p.Half.Duration, true)
+		return
+	}
+
+	for _, period := range p.Periods {
+		sched.Add(period.Name, period.Length.Duration, !period.Paused)
+	}
+
+	return
+}
+
 // FlagReceiver config
 type FlagReceiver struct {
 	Addr           string
-	ReceiveTimeout Duration
+	HTTPAddr       string
+	FlagsPerSecond int
+	FlagLifetime   int    // in rounds
+	FlagFormat     string // e.g. [A-Z0-9]{31}=, empty means default
 	SocketTimeout  Duration
 }
 
@@ -46,6 +82,37 @@ type AdvisoryReceiver struct {
 	Disabled       bool
 }
 
+// Scoring config
+type Scoring struct {
+	Formula string // classic, sqrt, faust, elo or sla
+	// Partial credit of states for SLA only, up is always 1
+	Mumble  float64
+	Corrupt float64
+}
+
+// Scheduler config, zero limit means unlimited
+type Scheduler struct {
+	MaxChecks        int // parallel checker runs in total
+	MaxServiceChecks int // parallel checker runs per service
+	MaxNetboxChecks  int // parallel checker runs per netbox
+	Jitter           Duration
+}
+
+// FlagCheck config, flags from previous rounds are checked in addition to
+// flags of current round
+type FlagCheck struct {
+	OldRounds  int // count of previous rounds, less than flag lifetime
+	OldSamples int // count of random flags from previous rounds
+}
+
+// Agent config, agents (tfh-agent) run checkers on netboxes
+type Agent struct {
+	Port int    // used if netbox address has no port
+	CA   string // signs certificates of jury and agents
+	Cert string // certificate of jury
+	Key  string
+}
+
 // Config config
 type Config struct {
 	LogFile        string
@@ -64,6 +131,10 @@ type Config struct {
 		AttackBuffer int
 	}
 	Pulse            Pulse
+	Scoring          Scoring
+	Scheduler        Scheduler
+	FlagCheck        FlagCheck
+	Agent            Agent
 	FlagReceiver     FlagReceiver
 	AdvisoryReceiver AdvisoryReceiver
 	Teams            []steward.Team
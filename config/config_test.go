@@ -11,6 +11,7 @@ package config_test
 import (
 	"log"
 	"testing"
+	"time"
 )
 
 import "github.com/jollheef/tin_foil_hat/config"
@@ -30,13 +31,86 @@ func TestReadConfig(*testing.T) {
 
 	bug_on_invalid("2015-08-02 15:04:00 +0300 MSK", cfg.Pulse.Start.String())
 
-	bug_on_invalid("4h0m0s", cfg.Pulse.Half.String())
+	sched := cfg.Pulse.Schedule()
 
-	bug_on_invalid("1h0m0s", cfg.Pulse.Lunch.String())
+	if len(sched.Periods) != 3 || sched.Periods[1].Name != "lunch" ||
+		sched.Periods[1].Running || !sched.Periods[2].Running {
+		log.Fatalln("Invalid periods:", sched.Periods)
+	}
+
+	bug_on_invalid("2015-08-03 00:04:00 +0300 MSK", sched.End().String())
 
 	bug_on_invalid("2m0s", cfg.Pulse.RoundLen.String())
 
 	bug_on_invalid("30s", cfg.Pulse.CheckTimeout.String())
 
+	bug_on_invalid(":8081", cfg.FlagReceiver.HTTPAddr)
+
+	bug_on_invalid("[A-Z0-9]{31}=", cfg.FlagReceiver.FlagFormat)
+
+	bug_on_invalid("20s", cfg.Scheduler.Jitter.String())
+
+	if cfg.FlagCheck.OldRounds != 3 || cfg.FlagCheck.OldSamples != 2 {
+		log.Fatalln("Invalid flag check config:", cfg.FlagCheck)
+	}
+
+	if cfg.Agent.Port != 9100 {
+		log.Fatalln("Parsed agent port", cfg.Agent.Port, "instead 9100")
+	}
+
+	bug_on_invalid("classic", cfg.Scoring.Formula)
+
+	if cfg.Scoring.Mumble != 0.5 {
+		log.Fatalln("Parsed mumble credit", cfg.Scoring.Mumble,
+			"instead 0.5")
+	}
+
+	if cfg.Services[1].Weight != 2 {
+		log.Fatalln("Parsed weight", cfg.Services[1].Weight, "instead 2")
+	}
+
+	if cfg.Services[1].Vulns != 2 {
+		log.Fatalln("Parsed vulns", cfg.Services[1].Vulns, "instead 2")
+	}
+
+	if cfg.Services[1].Workers != 4 {
+		log.Fatalln("Parsed workers", cfg.Services[1].Workers,
+			"instead 4")
+	}
+
+	if !cfg.Services[1].JSONProtocol {
+		log.Fatalln("Parsed json protocol is not enabled")
+	}
+
+	bug_on_invalid("http", cfg.Services[1].Probe)
+
+	bug_on_invalid("/health", cfg.Services[1].ProbeSend)
+
+	bug_on_invalid("PONG", cfg.Services[2].ProbeExpect)
+
 	// other values has built-in types
 }
+
+func TestLegacySchedule(*testing.T) {
+
+	var pulse config.Pulse
+
+	pulse.Start.Time = time.Date(2015, 8, 2, 12, 0, 0, 0, time.UTC)
+	pulse.Half.Duration = 4 * time.Hour
+
+	sched := pulse.Schedule()
+	if len(sched.Periods) != 2 || !sched.Periods[1].Running {
+		log.Fatalln("Invalid schedule without lunch:", sched.Periods)
+	}
+
+	pulse.Lunch.Duration = time.Hour
+
+	sched = pulse.Schedule()
+	if len(sched.Periods) != 3 || sched.Periods[1].Running {
+		log.Fatalln("Invalid schedule with lunch:", sched.Periods)
+	}
+
+	if !sched.End().Equal(pulse.Start.Add(9 * time.Hour)) {
+		log.Fatalln("Invalid end of game:", sched.End())
+	}
+}
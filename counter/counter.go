@@ -12,16 +12,18 @@ package counter
 
 import (
 	"database/sql"
+	"log"
 
 	"github.com/jollheef/tin_foil_hat/steward"
 )
 
-// CountStatesResult count round states (up/down/etc.) result
+// CountStatesResult count round states (up/down/etc.) result, only up is
+// counted, partial credit of states is used in SLA only
 func CountStatesResult(db *sql.DB, round, team int,
 	service steward.Service) (score float64, err error) {
 
-	halfStatus := steward.Status{round, team, service.ID,
-		steward.StatusUnknown}
+	halfStatus := steward.Status{Round: round, TeamID: team,
+		ServiceID: service.ID, State: steward.StatusUnknown}
 
 	states, err := steward.GetStates(db, halfStatus)
 	if err != nil {
@@ -44,13 +46,13 @@ func CountStatesResult(db *sql.DB, round, team int,
 	return
 }
 
-// CountDefenceResult count round defence result
+// CountDefenceResult count round defence result, services are weighted
 func CountDefenceResult(db *sql.DB, round, team int,
 	services []steward.Service) (defence float64, err error) {
 
 	defence = 0
 
-	perService := 1.0 / float64(len(services))
+	share := shares(services)
 
 	for _, svc := range services {
 
@@ -59,62 +61,117 @@ func CountDefenceResult(db *sql.DB, round, team int,
 			return defence, err
 		}
 
-		defence += score * perService
+		defence += score * share[svc.ID]
 	}
 
 	return
 }
 
-// CountRound count round result
-func CountRound(db *sql.DB, round int, teams []steward.Team,
-	services []steward.Service) (err error) {
+// InitialRating is rating of team before first round
+const InitialRating = 1000
 
-	roundRes := make(map[steward.Team]steward.RoundResult)
+// lastRating returns rating of team before round, if previous round is not
+// counted (e.g. count failed or daemon restarted) rating is taken from last
+// counted round
+func lastRating(db *sql.DB, teamID, round int) (rating float64, err error) {
 
-	for _, team := range teams {
+	rating = InitialRating
 
-		res := steward.RoundResult{TeamID: team.ID, Round: round}
+	prev, err := steward.GetRoundResult(db, teamID, round-1)
+	if err == sql.ErrNoRows {
+		prev, err = steward.GetLastResult(db, teamID)
+	}
 
-		def, err := CountDefenceResult(db, round, team.ID, services)
-		if err != nil {
-			return err
+	if err == sql.ErrNoRows {
+		if round > 1 {
+			log.Printf("No result of team %d before round %d, "+
+				"use initial rating", teamID, round)
 		}
+		return rating, nil
+	}
 
-		res.DefenceScore = def * 2
+	if err != nil {
+		return
+	}
 
-		roundRes[team] = res
+	if prev.Rating != 0 {
+		rating = prev.Rating
 	}
 
-	perService := 1.0 / float64(len(services))
+	return
+}
+
+// CollectRound collect data required for count round, captures are
+// credited to the round in which flag was submitted, not to the round of flag
+func CollectRound(db *sql.DB, round int, teams []steward.Team,
+	services []steward.Service) (r Round, err error) {
+
+	r.ID = round
+	r.Teams = teams
+	r.Services = services
+	r.Uptime = make(map[int]map[int]float64)
+	r.Before = make(map[int]int)
+	r.Ratings = make(map[int]float64)
 
 	for _, team := range teams {
 
-		cflags, err := steward.GetCapturedFlags(db, round, team.ID)
+		r.Ratings[team.ID], err = lastRating(db, team.ID, round)
 		if err != nil {
-			return err
+			return
 		}
 
-		for _, flag := range cflags {
+		r.Uptime[team.ID] = make(map[int]float64)
+
+		for _, svc := range services {
 
-			attackedTeam, err := steward.GetTeam(db, flag.TeamID)
+			score, err := CountStatesResult(db, round, team.ID, svc)
 			if err != nil {
-				return err
+				return r, err
 			}
 
-			res := roundRes[attackedTeam]
-			res.DefenceScore -= perService
-			if res.DefenceScore < 0 {
-				res.DefenceScore = 0
+			r.Uptime[team.ID][svc.ID] = score
+		}
+	}
+
+	for _, team := range teams {
+
+		cflags, err := steward.GetCapturedFlags(db, round, team.ID)
+		if err != nil {
+			return r, err
+		}
+
+		for _, flag := range cflags {
+
+			r.Captures = append(r.Captures,
+				Capture{Flag: flag, Attacker: team.ID})
+
+			if _, ok := r.Before[flag.ID]; ok {
+				continue
 			}
-			roundRes[attackedTeam] = res
 
-			attackRes := roundRes[team]
-			attackRes.AttackScore += perService
-			roundRes[team] = attackRes
+			r.Before[flag.ID], err = steward.CaptureCount(db,
+				flag.ID, round)
+			if err != nil {
+				return r, err
+			}
 		}
 	}
 
-	for _, res := range roundRes {
+	return
+}
+
+// CountRound count round result with current scorer (see SetScorer)
+func CountRound(db *sql.DB, round int, teams []steward.Team,
+	services []steward.Service) (err error) {
+
+	r, err := CollectRound(db, round, teams, services)
+	if err != nil {
+		return
+	}
+
+	scores := scorer.Count(r)
+
+	for _, res := range scores.Teams {
 		_, err := steward.AddRoundResult(db, res)
 		if err != nil {
 			return err
@@ -122,5 +179,14 @@ func CountRound(db *sql.DB, round int, teams []steward.Team,
 
 	}
 
+	for _, services := range scores.Services {
+		for _, res := range services {
+			_, err := steward.AddServiceResult(db, res)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	return
 }
@@ -49,6 +49,7 @@ func (t testDB) Close() {
 	t.db.Exec("DROP TABLE status")
 	t.db.Exec("DROP TABLE round")
 	t.db.Exec("DROP TABLE round_result")
+	t.db.Exec("DROP TABLE service_result")
 
 	t.db.Close()
 }
@@ -78,6 +79,11 @@ func TestCountStatesResult(*testing.T) {
 	steward.PutStatus(db.db, steward.Status{Round: r, TeamID: t,
 		ServiceID: s, State: steward.StatusMumble})
 
+	// Partial credit affects SLA only
+	counter.SetStateCredit(steward.StatusMumble, 0.5)
+
+	defer counter.SetStateCredit(steward.StatusMumble, 0)
+
 	res, err := counter.CountStatesResult(db.db, r, t, svc)
 	if err != nil {
 		log.Fatalln("Count states failed:", err)
@@ -91,6 +97,52 @@ func TestCountStatesResult(*testing.T) {
 
 }
 
+func TestCountSLA(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	t := 1 // team id
+	s := 1 // service id
+
+	svc := steward.Service{ID: s, Name: "foo", Port: 8080}
+
+	states := []steward.ServiceState{steward.StatusUP,
+		steward.StatusMumble, steward.StatusDown, steward.StatusUP}
+
+	for i, state := range states {
+		steward.PutStatus(db.db, steward.Status{Round: i + 1, TeamID: t,
+			ServiceID: s, State: state})
+	}
+
+	counter.SetStateCredit(steward.StatusMumble, 0.5)
+
+	defer counter.SetStateCredit(steward.StatusMumble, 0)
+
+	sla, err := counter.CountSLA(db.db, len(states), t, svc)
+	if err != nil {
+		log.Fatalln("Count SLA failed:", err)
+	}
+
+	if sla != 2.5/4 {
+		log.Fatalln("SLA invalid:", sla, "instead", 2.5/4)
+	}
+
+	// Only previous rounds
+	sla, err = counter.CountSLA(db.db, 2, t, svc)
+	if err != nil {
+		log.Fatalln("Count SLA failed:", err)
+	}
+
+	if sla != 0.75 {
+		log.Fatalln("SLA invalid:", sla, "instead", 0.75)
+	}
+}
+
 func TestCountDefenceResult(*testing.T) {
 
 	db, err := openDB()
@@ -204,7 +256,7 @@ func TestCountRound(*testing.T) {
 
 	fillTestServices(db.db)
 
-	priv, err := vexillary.GenerateKey()
+	key, err := vexillary.GenerateKey()
 	if err != nil {
 		log.Fatalln("Generate key failed:", err)
 	}
@@ -229,7 +281,8 @@ func TestCountRound(*testing.T) {
 	for _, team := range teams {
 		for _, svc := range services {
 
-			flag, err := vexillary.GenerateFlag(priv)
+			flag, err := vexillary.GenerateFlag(key, round,
+				team.ID, svc.ID)
 			if err != nil {
 				log.Fatalln("Generate flag failed:", err)
 			}
@@ -258,7 +311,7 @@ func TestCountRound(*testing.T) {
 		log.Fatalln("Get flag info failed:", err)
 	}
 
-	err = steward.CaptureFlag(db.db, flag1.ID, teams[2].ID)
+	err = steward.CaptureFlag(db.db, flag1.ID, teams[2].ID, round)
 	if err != nil {
 		log.Fatalln("Capture flag failed:", err)
 	}
@@ -268,7 +321,7 @@ func TestCountRound(*testing.T) {
 		log.Fatalln("Get flag info failed:", err)
 	}
 
-	err = steward.CaptureFlag(db.db, flag2.ID, teams[3].ID)
+	err = steward.CaptureFlag(db.db, flag2.ID, teams[3].ID, round)
 	if err != nil {
 		log.Fatalln("Capture flag failed:", err)
 	}
@@ -299,3 +352,133 @@ func TestCountRound(*testing.T) {
 	}
 
 }
+
+func TestCollectRoundRating(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	fillTestTeams(db.db)
+
+	teams, err := steward.GetTeams(db.db)
+	if err != nil {
+		log.Fatalln("Get teams failed:", err)
+	}
+
+	_, err = steward.AddRoundResult(db.db, steward.RoundResult{
+		TeamID: teams[0].ID, Round: 1, Rating: 1200})
+	if err != nil {
+		log.Fatalln("Add round result failed:", err)
+	}
+
+	// Round 2 is not counted, rating is kept from round 1
+	r, err := counter.CollectRound(db.db, 3, teams, nil)
+	if err != nil {
+		log.Fatalln("Collect round failed:", err)
+	}
+
+	if r.Ratings[teams[0].ID] != 1200 {
+		log.Fatalln("Rating is not kept:", r.Ratings[teams[0].ID])
+	}
+
+	// Team without results has initial rating
+	if r.Ratings[teams[1].ID] != counter.InitialRating {
+		log.Fatalln("Invalid initial rating:", r.Ratings[teams[1].ID])
+	}
+}
+
+func TestCountRoundSeveralAttackers(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	fillTestTeams(db.db)
+
+	fillTestServices(db.db)
+
+	round, err := steward.NewRound(db.db, time.Minute)
+	if err != nil {
+		log.Fatalln("Create new round failed:", err)
+	}
+
+	teams, err := steward.GetTeams(db.db)
+	if err != nil {
+		log.Fatalln("Get teams failed:", err)
+	}
+
+	services, err := steward.GetServices(db.db)
+	if err != nil {
+		log.Fatalln("Get services failed:", err)
+	}
+
+	for _, team := range teams {
+		for _, svc := range services {
+			err = steward.PutStatus(db.db, steward.Status{
+				Round: round, TeamID: team.ID,
+				ServiceID: svc.ID, State: steward.StatusUP})
+			if err != nil {
+				log.Fatalln("Put status to database failed:", err)
+			}
+		}
+	}
+
+	flg := steward.Flag{ID: 1, Flag: "f", Round: round,
+		TeamID: teams[0].ID, ServiceID: services[0].ID}
+
+	err = steward.AddFlag(db.db, flg)
+	if err != nil {
+		log.Fatalln("Add flag to database failed:", err)
+	}
+
+	// Same flag captured by two teams
+	for _, team := range teams[2:] {
+		err = steward.CaptureFlag(db.db, flg.ID, team.ID, round)
+		if err != nil {
+			log.Fatalln("Capture flag failed:", err)
+		}
+	}
+
+	// Second capture by same team is not allowed
+	err = steward.CaptureFlag(db.db, flg.ID, teams[2].ID, round)
+	if err == nil {
+		log.Fatalln("Flag captured twice by one team")
+	}
+
+	err = counter.CountRound(db.db, round, teams, services)
+	if err != nil {
+		log.Fatalln("Count round failed:", err)
+	}
+
+	// Victim lose flag only once
+	res, err := steward.GetRoundResult(db.db, teams[0].ID, round)
+	if err != nil || res.AttackScore != 0.0 || res.DefenceScore != 1.75 {
+		log.Fatalln("Invalid result:", res)
+	}
+
+	for _, team := range teams[2:] {
+		res, err = steward.GetRoundResult(db.db, team.ID, round)
+		if err != nil || res.AttackScore != 0.25 ||
+			res.DefenceScore != 2.0 {
+			log.Fatalln("Invalid result:", res)
+		}
+	}
+
+	// Loss is accounted in service of flag
+	srs, err := steward.GetServiceResults(db.db, teams[0].ID)
+	if err != nil {
+		log.Fatalln("Get service results failed:", err)
+	}
+
+	if len(srs) != len(services) || srs[0].DefenceScore != 0.25 ||
+		srs[1].DefenceScore != 0.5 {
+		log.Fatalln("Invalid service results:", srs)
+	}
+}
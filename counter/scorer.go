@@ -0,0 +1,393 @@
+/**
+ * @file scorer.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief scoring formulas
+ *
+ * Contain scorer interface and implementations of several rulesets
+ */
+
+package counter
+
+import (
+	"errors"
+	"math"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Capture contains info about flag captured in round
+type Capture struct {
+	Flag     steward.Flag
+	Attacker int // team id
+}
+
+// Round contains all data required for count round result
+type Round struct {
+	ID       int
+	Teams    []steward.Team
+	Services []steward.Service
+	// { team id : { service id : part of checks with status up } }
+	Uptime map[int]map[int]float64
+	// flags captured in this round
+	Captures []Capture
+	// { flag id : captures of flag in previous rounds }
+	Before map[int]int
+	// { team id : rating after previous round }
+	Ratings map[int]float64
+}
+
+// Scores contains round result of each team with breakdown by services,
+// breakdown is not affected by clamp of team defence
+type Scores struct {
+	Teams map[int]steward.RoundResult // { team id : result }
+	// { team id : { service id : result } }
+	Services map[int]map[int]steward.ServiceResult
+}
+
+// Scorer count round result for each team
+type Scorer interface {
+	Count(r Round) Scores
+}
+
+var scorers = map[string]Scorer{
+	"classic": classicScorer{},
+	"sqrt":    sqrtScorer{},
+	"faust":   faustScorer{},
+	"elo":     eloScorer{},
+	"sla":     slaScorer{},
+}
+
+// NewScorer returns scorer by name, empty name means classic
+func NewScorer(name string) (s Scorer, err error) {
+
+	if name == "" {
+		name = "classic"
+	}
+
+	s, ok := scorers[name]
+	if !ok {
+		err = errors.New("unknown scoring formula " + name)
+		return
+	}
+
+	return
+}
+
+var scorer Scorer = classicScorer{}
+
+// SetScorer set scorer used by CountRound
+func SetScorer(s Scorer) {
+	scorer = s
+}
+
+// lostFlag contains info about flag, which was captured in round
+type lostFlag struct {
+	flag   steward.Flag
+	before int // captures in previous rounds
+	now    int // captures in this round
+}
+
+func lostFlags(r Round) (lost map[int]*lostFlag) {
+
+	lost = make(map[int]*lostFlag) // { flag id : info }
+
+	for _, c := range r.Captures {
+		l, ok := lost[c.Flag.ID]
+		if !ok {
+			l = &lostFlag{flag: c.Flag, before: r.Before[c.Flag.ID]}
+			lost[c.Flag.ID] = l
+		}
+		l.now++
+	}
+
+	return
+}
+
+// Weight returns weight of service, zero weight means one
+func Weight(svc steward.Service) float64 {
+	if svc.Weight <= 0 {
+		return 1
+	}
+	return svc.Weight
+}
+
+// shares returns { service id : weight / sum of weights }
+func shares(services []steward.Service) (share map[int]float64) {
+
+	share = make(map[int]float64)
+
+	total := 0.0
+	for _, svc := range services {
+		total += Weight(svc)
+	}
+
+	for _, svc := range services {
+		share[svc.ID] = Weight(svc) / total
+	}
+
+	return
+}
+
+// flagShares returns { service id : share / count of vulns }, so service
+// with several flag stores does not cost more than others
+func flagShares(services []steward.Service) (share map[int]float64) {
+
+	share = shares(services)
+
+	for _, svc := range services {
+		share[svc.ID] /= float64(steward.VulnCount(svc))
+	}
+
+	return
+}
+
+func newScores(r Round) (s Scores) {
+
+	s.Teams = make(map[int]steward.RoundResult)
+	s.Services = make(map[int]map[int]steward.ServiceResult)
+
+	for _, team := range r.Teams {
+		s.Teams[team.ID] = steward.RoundResult{TeamID: team.ID,
+			Round: r.ID, Rating: r.Ratings[team.ID]}
+
+		s.Services[team.ID] = make(map[int]steward.ServiceResult)
+
+		for _, svc := range r.Services {
+			s.Services[team.ID][svc.ID] = steward.ServiceResult{
+				TeamID: team.ID, ServiceID: svc.ID, Round: r.ID}
+		}
+	}
+
+	return
+}
+
+func addScore(s Scores, teamID, serviceID int, attack, defence float64) {
+
+	tr, ok := s.Teams[teamID]
+	if !ok {
+		return
+	}
+
+	tr.AttackScore += attack
+	tr.DefenceScore += defence
+
+	s.Teams[teamID] = tr
+
+	sr, ok := s.Services[teamID][serviceID]
+	if !ok {
+		return
+	}
+
+	sr.AttackScore += attack
+	sr.DefenceScore += defence
+
+	s.Services[teamID][serviceID] = sr
+}
+
+func clampDefence(s Scores) {
+	for id, tr := range s.Teams {
+		if tr.DefenceScore < 0 {
+			tr.DefenceScore = 0
+			s.Teams[id] = tr
+		}
+	}
+}
+
+// uptimeDefence add defence for uptime, scale is defence for full uptime
+// in service with weight 1
+func uptimeDefence(s Scores, r Round, scale func(svc steward.Service) float64) {
+	for _, team := range r.Teams {
+		for _, svc := range r.Services {
+			addScore(s, team.ID, svc.ID, 0,
+				r.Uptime[team.ID][svc.ID]*scale(svc))
+		}
+	}
+}
+
+// classicScorer: defence is 2 * uptime, each lost flag subtract
+// share of service (only once per flag), each captured flag give share of
+// service to attacker, share is weight of service / sum of weights, flag
+// costs share / count of vulns
+type classicScorer struct{}
+
+func (classicScorer) Count(r Round) (s Scores) {
+
+	s = newScores(r)
+
+	share := shares(r.Services)
+	flagShare := flagShares(r.Services)
+
+	uptimeDefence(s, r, func(svc steward.Service) float64 {
+		return 2 * share[svc.ID]
+	})
+
+	for _, c := range r.Captures {
+		addScore(s, c.Attacker, c.Flag.ServiceID,
+			flagShare[c.Flag.ServiceID], 0)
+	}
+
+	for _, l := range lostFlags(r) {
+		if l.before == 0 {
+			addScore(s, l.flag.TeamID, l.flag.ServiceID, 0,
+				-flagShare[l.flag.ServiceID])
+		}
+	}
+
+	clampDefence(s)
+
+	return
+}
+
+// sqrtScorer: defence as in classic, flag captured by n teams cost
+// sqrt(n)*share for victim, and each attacker get share/sqrt(n), so flag
+// stolen by everybody is cheap for attackers
+type sqrtScorer struct{}
+
+func (sqrtScorer) Count(r Round) (s Scores) {
+
+	s = newScores(r)
+
+	share := shares(r.Services)
+	flagShare := flagShares(r.Services)
+
+	uptimeDefence(s, r, func(svc steward.Service) float64 {
+		return 2 * share[svc.ID]
+	})
+
+	lost := lostFlags(r)
+
+	for _, c := range r.Captures {
+		n := float64(lost[c.Flag.ID].before + lost[c.Flag.ID].now)
+		addScore(s, c.Attacker, c.Flag.ServiceID,
+			flagShare[c.Flag.ServiceID]/math.Sqrt(n), 0)
+	}
+
+	for _, l := range lost {
+		// victim pays only for new captures
+		cost := math.Sqrt(float64(l.before+l.now)) -
+			math.Sqrt(float64(l.before))
+		addScore(s, l.flag.TeamID, l.flag.ServiceID, 0,
+			-cost*flagShare[l.flag.ServiceID])
+	}
+
+	clampDefence(s)
+
+	return
+}
+
+// faustScorer: FAUST CTF like formula, each captured flag give 1 + 1/n to
+// attacker, where n is count of teams captured flag, victim lose n^0.75
+// for flag, and sla part of defence is uptime * sqrt(teams). All values
+// are multiplied by weight of service, flag values are also divided by
+// count of vulns. Defence can be negative.
+type faustScorer struct{}
+
+func (faustScorer) Count(r Round) (s Scores) {
+
+	s = newScores(r)
+
+	weight := make(map[int]float64)
+	for _, svc := range r.Services {
+		weight[svc.ID] = Weight(svc)
+	}
+
+	sla := math.Sqrt(float64(len(r.Teams)))
+
+	uptimeDefence(s, r, func(svc steward.Service) float64 {
+		return sla * weight[svc.ID]
+	})
+
+	lost := lostFlags(r)
+
+	flagWeight := make(map[int]float64)
+	for _, svc := range r.Services {
+		flagWeight[svc.ID] = weight[svc.ID] /
+			float64(steward.VulnCount(svc))
+	}
+
+	for _, c := range r.Captures {
+		n := float64(lost[c.Flag.ID].before + lost[c.Flag.ID].now)
+		addScore(s, c.Attacker, c.Flag.ServiceID,
+			(1+1/n)*flagWeight[c.Flag.ServiceID], 0)
+	}
+
+	for _, l := range lost {
+		cost := math.Pow(float64(l.before+l.now), 0.75) -
+			math.Pow(float64(l.before), 0.75)
+		addScore(s, l.flag.TeamID, l.flag.ServiceID, 0,
+			-cost*flagWeight[l.flag.ServiceID])
+	}
+
+	return
+}
+
+const eloK = 16 // max rating change per capture
+
+// expected returns Elo expected score of team a against team b
+func expected(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// eloScorer: defence as in classic, flag value depends on ratings of
+// attacker and victim: flag of equal team cost share of service, flag of
+// much stronger team up to double share, flag of much weaker team near zero.
+// Victim lose mean value of flag over attackers of this round (only once
+// per flag). Ratings are updated after each round as in Elo system.
+type eloScorer struct{}
+
+func (eloScorer) Count(r Round) (s Scores) {
+
+	s = newScores(r)
+
+	share := shares(r.Services)
+	flagShare := flagShares(r.Services)
+
+	uptimeDefence(s, r, func(svc steward.Service) float64 {
+		return 2 * share[svc.ID]
+	})
+
+	value := make(map[int]float64) // { flag id : sum of values }
+
+	ratings := make(map[int]float64)
+	for id, rating := range r.Ratings {
+		ratings[id] = rating
+	}
+
+	for _, c := range r.Captures {
+
+		e := expected(r.Ratings[c.Attacker], r.Ratings[c.Flag.TeamID])
+
+		v := 2 * (1 - e) * flagShare[c.Flag.ServiceID]
+
+		addScore(s, c.Attacker, c.Flag.ServiceID, v, 0)
+		value[c.Flag.ID] += v
+
+		ratings[c.Attacker] += eloK * (1 - e)
+		ratings[c.Flag.TeamID] -= eloK * (1 - e)
+	}
+
+	for id, l := range lostFlags(r) {
+		if l.before == 0 {
+			addScore(s, l.flag.TeamID, l.flag.ServiceID, 0,
+				-value[id]/float64(l.now))
+		}
+	}
+
+	clampDefence(s)
+
+	for id, tr := range s.Teams {
+		tr.Rating = ratings[id]
+		s.Teams[id] = tr
+	}
+
+	return
+}
+
+// slaScorer: rounds are counted as classic, SLA is applied to totals of
+// the whole game by scoreboard (see ApplySLA), so final score is sum of
+// (attack + defence) * SLA over services
+type slaScorer struct {
+	classicScorer
+}
@@ -0,0 +1,262 @@
+/**
+ * @file scorer_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test scoring formulas
+ */
+
+package counter_test
+
+import (
+	"log"
+	"math"
+	"testing"
+)
+
+import (
+	"github.com/jollheef/tin_foil_hat/counter"
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// Four teams and four services with full uptime, flag of first team
+// captured by third and fourth team, flag of second team captured by
+// fourth team in previous round and by third team in this round.
+func testRound() (r counter.Round) {
+
+	r.ID = 2
+	r.Uptime = make(map[int]map[int]float64)
+	r.Before = make(map[int]int)
+	r.Ratings = make(map[int]float64)
+
+	for id := 1; id <= 4; id++ {
+		r.Teams = append(r.Teams, steward.Team{ID: id})
+		r.Services = append(r.Services, steward.Service{ID: id})
+		r.Ratings[id] = counter.InitialRating
+	}
+
+	for _, team := range r.Teams {
+		r.Uptime[team.ID] = make(map[int]float64)
+		for _, svc := range r.Services {
+			r.Uptime[team.ID][svc.ID] = 1
+		}
+	}
+
+	flg1 := steward.Flag{ID: 1, Round: 2, TeamID: 1, ServiceID: 1}
+	flg2 := steward.Flag{ID: 2, Round: 1, TeamID: 2, ServiceID: 1}
+
+	r.Captures = []counter.Capture{
+		{Flag: flg1, Attacker: 3},
+		{Flag: flg1, Attacker: 4},
+		{Flag: flg2, Attacker: 3},
+	}
+
+	r.Before[flg1.ID] = 0
+	r.Before[flg2.ID] = 1
+
+	return
+}
+
+func equal(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func checkResult(res counter.Scores, team int, attack, defence float64) {
+
+	if !equal(res.Teams[team].AttackScore, attack) ||
+		!equal(res.Teams[team].DefenceScore, defence) {
+		log.Fatalf("Invalid result of team %d: %v instead %f/%f",
+			team, res.Teams[team], attack, defence)
+	}
+}
+
+func TestNewScorer(*testing.T) {
+
+	for _, name := range []string{"", "classic", "sqrt", "faust", "elo",
+		"sla"} {
+		_, err := counter.NewScorer(name)
+		if err != nil {
+			log.Fatalln("Get scorer failed:", err)
+		}
+	}
+
+	_, err := counter.NewScorer("unknown")
+	if err == nil {
+		log.Fatalln("Get unknown scorer not failed")
+	}
+}
+
+func TestClassicScorer(*testing.T) {
+
+	scorer, _ := counter.NewScorer("classic")
+
+	res := scorer.Count(testRound())
+
+	checkResult(res, 1, 0, 1.75)
+	checkResult(res, 2, 0, 2) // flag lost in previous round
+	checkResult(res, 3, 0.5, 2)
+	checkResult(res, 4, 0.25, 2)
+}
+
+func TestSqrtScorer(*testing.T) {
+
+	scorer, _ := counter.NewScorer("sqrt")
+
+	res := scorer.Count(testRound())
+
+	checkResult(res, 1, 0, 2-math.Sqrt(2)*0.25)
+	checkResult(res, 2, 0, 2-(math.Sqrt(2)-1)*0.25)
+	checkResult(res, 3, 0.25/math.Sqrt(2)*2, 2)
+	checkResult(res, 4, 0.25/math.Sqrt(2), 2)
+}
+
+func TestFaustScorer(*testing.T) {
+
+	scorer, _ := counter.NewScorer("faust")
+
+	res := scorer.Count(testRound())
+
+	sla := 4 * math.Sqrt(4)
+
+	checkResult(res, 1, 0, sla-math.Pow(2, 0.75))
+	checkResult(res, 2, 0, sla-(math.Pow(2, 0.75)-1))
+	checkResult(res, 3, 1.5*2, sla)
+	checkResult(res, 4, 1.5, sla)
+}
+
+func TestEloScorer(*testing.T) {
+
+	scorer, _ := counter.NewScorer("elo")
+
+	// Equal teams, flag cost as in classic formula
+	res := scorer.Count(testRound())
+
+	checkResult(res, 1, 0, 1.75)
+	checkResult(res, 2, 0, 2)
+	checkResult(res, 3, 0.5, 2)
+	checkResult(res, 4, 0.25, 2)
+
+	if !equal(res.Teams[3].Rating, counter.InitialRating+2*8) ||
+		!equal(res.Teams[1].Rating, counter.InitialRating-2*8) {
+		log.Fatalln("Invalid ratings:", res)
+	}
+
+	// Flag of strong team is more valuable
+	r := testRound()
+	r.Ratings[1] = counter.InitialRating + 400
+
+	res = scorer.Count(r)
+
+	value := 2 * (1 - 1.0/11) * 0.25
+
+	checkResult(res, 1, 0, 2-value)
+	checkResult(res, 4, value, 2)
+
+	// Rating is kept by other scorers
+	classic, _ := counter.NewScorer("classic")
+
+	res = classic.Count(r)
+
+	if res.Teams[1].Rating != r.Ratings[1] {
+		log.Fatalln("Rating is not kept:", res.Teams[1].Rating)
+	}
+}
+
+func TestWeightedScorer(*testing.T) {
+
+	r := testRound()
+
+	// First service costs as other three
+	r.Services[0].Weight = 3
+	for i := 1; i < len(r.Services); i++ {
+		r.Services[i].Weight = 1
+	}
+
+	scorer, _ := counter.NewScorer("classic")
+
+	res := scorer.Count(r)
+
+	checkResult(res, 1, 0, 1.5)
+	checkResult(res, 3, 1, 2)
+	checkResult(res, 4, 0.5, 2)
+
+	// Per-service breakdown
+	svc1 := res.Services[1][1]
+	if !equal(svc1.AttackScore, 0) || !equal(svc1.DefenceScore, 0.5) {
+		log.Fatalln("Invalid service result:", svc1)
+	}
+
+	svc2 := res.Services[1][2]
+	if !equal(svc2.AttackScore, 0) || !equal(svc2.DefenceScore, 1.0/3) {
+		log.Fatalln("Invalid service result:", svc2)
+	}
+
+	svc1 = res.Services[3][1]
+	if !equal(svc1.AttackScore, 1) || !equal(svc1.DefenceScore, 1) {
+		log.Fatalln("Invalid service result:", svc1)
+	}
+}
+
+func TestSLAScorer(*testing.T) {
+
+	scorer, _ := counter.NewScorer("sla")
+
+	// Round is counted as classic
+	res := scorer.Count(testRound())
+
+	checkResult(res, 1, 0, 1.75)
+	checkResult(res, 3, 0.5, 2)
+
+	// Results of third team summed over three rounds, as in database
+	results := make([]steward.ServiceResult, 4)
+	for round := 1; round <= 3; round++ {
+		r := testRound()
+		r.ID = round
+
+		for id, sr := range scorer.Count(r).Services[3] {
+			results[id-1].ServiceID = id
+			results[id-1].AttackScore += sr.AttackScore
+			results[id-1].DefenceScore += sr.DefenceScore
+		}
+	}
+
+	// SLA of first service was 1, 0.5 and 0.75 after each round, and
+	// second service was down whole game, only SLA of whole game is used
+	sla := map[int]float64{1: 0.75, 2: 0, 3: 1, 4: 1}
+
+	attack, defence := counter.ApplySLA(results, sla)
+
+	// (attack + defence) * SLA: first service 1.5 + 1.5, others 0 + 1.5
+	if !equal(attack, 1.5*0.75) ||
+		!equal(defence, 1.5*0.75+1.5*0+1.5+1.5) {
+		log.Fatalln("Invalid total of game:", attack, defence)
+	}
+
+	if !equal(results[0].AttackScore, 1.5*0.75) ||
+		!equal(results[1].DefenceScore, 0) {
+		log.Fatalln("Invalid service result:", results)
+	}
+
+	// Defence is clamped as in rounds
+	_, defence = counter.ApplySLA([]steward.ServiceResult{
+		{ServiceID: 1, DefenceScore: -1}}, sla)
+	if defence != 0 {
+		log.Fatalln("Negative defence:", defence)
+	}
+}
+
+func TestVulnScorer(*testing.T) {
+
+	r := testRound()
+
+	// Flag of service with two vulns costs half
+	r.Services[0].Vulns = 2
+
+	scorer, _ := counter.NewScorer("classic")
+
+	res := scorer.Count(r)
+
+	checkResult(res, 1, 0, 2-0.125)
+	checkResult(res, 3, 0.25, 2)
+	checkResult(res, 4, 0.125, 2)
+}
@@ -0,0 +1,88 @@
+/**
+ * @file sla.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief count service level agreement
+ *
+ * Contain functions for count availability of services over the whole game
+ */
+
+package counter
+
+import (
+	"database/sql"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+// { state : part of up }, states not in map have no credit
+var credits = map[steward.ServiceState]float64{
+	steward.StatusUP: 1,
+}
+
+// SetStateCredit set partial credit of state in SLA, e.g. 0.5 for mumble,
+// credit is clamped to [0, 1], uptime and defence are not affected
+func SetStateCredit(state steward.ServiceState, credit float64) {
+
+	if credit < 0 {
+		credit = 0
+	} else if credit > 1 {
+		credit = 1
+	}
+
+	credits[state] = credit
+}
+
+// Credit returns part of up for state in SLA
+func Credit(state steward.ServiceState) float64 {
+	return credits[state]
+}
+
+// CountSLA count availability of team service from first round to given
+// round inclusive, states are weighted by credit
+func CountSLA(db *sql.DB, round, team int, service steward.Service) (
+	sla float64, err error) {
+
+	counts, err := steward.GetStateCounts(db, round, team, service.ID)
+	if err != nil {
+		return
+	}
+
+	total := 0
+	for state, count := range counts {
+		sla += Credit(state) * float64(count)
+		total += count
+	}
+
+	if total == 0 {
+		return
+	}
+
+	sla /= float64(total)
+
+	return
+}
+
+// ApplySLA multiply attack and defence of each service summed over all
+// rounds by SLA of service ({ service id : sla }), returns totals of team,
+// defence is clamped to zero as in rounds
+func ApplySLA(results []steward.ServiceResult, sla map[int]float64) (
+	attack, defence float64) {
+
+	for i := range results {
+		res := &results[i]
+
+		res.AttackScore *= sla[res.ServiceID]
+		res.DefenceScore *= sla[res.ServiceID]
+
+		attack += res.AttackScore
+		defence += res.DefenceScore
+	}
+
+	if defence < 0 {
+		defence = 0
+	}
+
+	return
+}
@@ -19,7 +19,9 @@ import (
 	"time"
 
 	"github.com/jollheef/tin_foil_hat/checker"
+	"github.com/jollheef/tin_foil_hat/checker/builtin"
 	"github.com/jollheef/tin_foil_hat/config"
+	"github.com/jollheef/tin_foil_hat/counter"
 	"github.com/jollheef/tin_foil_hat/pulse"
 	"github.com/jollheef/tin_foil_hat/receiver"
 	"github.com/jollheef/tin_foil_hat/scoreboard"
@@ -53,12 +55,29 @@ func buildInfo() (str string) {
 	return
 }
 
+func addFlagKey(db *sql.DB) (err error) {
+
+	key, err := vexillary.GenerateKey()
+	if err != nil {
+		return
+	}
+
+	id, err := steward.AddFlagKey(db, key)
+	if err != nil {
+		return
+	}
+
+	log.Println("Flag key", id, "generated")
+
+	return
+}
+
 func reinitDatabase(db *sql.DB, config config.Config) {
 
 	var err error
 
 	if config.Database.SafeReinit {
-		if time.Now().After(config.Pulse.Start.Time) {
+		if time.Now().After(config.Pulse.Schedule().Start) {
 			log.Fatalln("Reinit after start not allowed")
 		}
 	}
@@ -88,7 +107,8 @@ func reinitDatabase(db *sql.DB, config config.Config) {
 			network = "tcp"
 		}
 
-		log.Printf("Add service %s (%s)\n", svc.Name, network)
+		log.Printf("Add service %s (%s, weight %.2f)\n", svc.Name,
+			network, counter.Weight(svc))
 
 		err = steward.AddService(db, svc)
 		if err != nil {
@@ -144,24 +164,126 @@ func main() {
 		reinitDatabase(db, config)
 	}
 
+	sched := config.Pulse.Schedule()
+
+	err = sched.Validate()
+	if err != nil {
+		log.Fatalln("Invalid schedule:", err)
+	}
+
+	for _, p := range sched.Periods {
+		log.Println("Period", p.Name, p.Start, "-", p.End,
+			"running:", p.Running)
+	}
+
 	checker.SetTimeout(config.CheckerTimeout.Duration)
 
+	checker.SetLimits(config.Scheduler.MaxChecks,
+		config.Scheduler.MaxServiceChecks,
+		config.Scheduler.MaxNetboxChecks)
+	checker.SetJitter(config.Scheduler.Jitter.Duration)
+
+	if config.FlagReceiver.FlagLifetime < 0 {
+		log.Fatalln("Flag lifetime must not be negative, not",
+			config.FlagReceiver.FlagLifetime)
+	}
+
+	// Only flags from current round are valid if lifetime is not set
+	if config.FlagReceiver.FlagLifetime == 0 {
+		config.FlagReceiver.FlagLifetime = 1
+	}
+
+	// Flags older than lifetime can be already removed by teams
+	oldRounds := config.FlagCheck.OldRounds
+	if oldRounds >= config.FlagReceiver.FlagLifetime {
+		oldRounds = config.FlagReceiver.FlagLifetime - 1
+		log.Println("Check of flags limited to", oldRounds,
+			"previous rounds by flag lifetime")
+	}
+
+	checker.SetOldFlags(oldRounds, config.FlagCheck.OldSamples)
+
+	builtin.Register()
+
+	for _, svc := range config.Services {
+		err = checker.ValidService(svc)
+		if err != nil {
+			log.Fatalln("Invalid service:", err)
+		}
+	}
+
+	useNetbox := false
+
+	for _, team := range config.Teams {
+		_, err = steward.ParseSubnets(team.Subnet)
+		if err != nil {
+			log.Fatalln("Invalid subnet of team", team.Name+":", err)
+		}
+
+		useNetbox = useNetbox || team.UseNetbox
+	}
+
+	// Certificates are required only for checks by agents on netboxes
+	if useNetbox {
+		agentTLS, err := checker.JuryTLSConfig(config.Agent.CA,
+			config.Agent.Cert, config.Agent.Key)
+		if err != nil {
+			log.Fatalln("Load agent certificates fail:", err)
+		}
+
+		checker.SetAgent(config.Agent.Port, agentTLS)
+	}
+
+	scorer, err := counter.NewScorer(config.Scoring.Formula)
+	if err != nil {
+		log.Fatalln("Invalid scoring:", err)
+	}
+
+	counter.SetScorer(scorer)
+
+	if config.Scoring.Formula == "sla" {
+		scoreboard.EnableSLA()
+	}
+
+	counter.SetStateCredit(steward.StatusMumble, config.Scoring.Mumble)
+	counter.SetStateCredit(steward.StatusCorrupt, config.Scoring.Corrupt)
+
 	if config.AdvisoryReceiver.Disabled {
 		scoreboard.DisableAdvisory()
 	}
 
-	priv, err := vexillary.GenerateKey()
+	if config.FlagReceiver.FlagFormat != "" {
+		err = vexillary.SetFormat(config.FlagReceiver.FlagFormat)
+		if err != nil {
+			log.Fatalln("Invalid flag format:", err)
+		}
+	}
+
+	// Key is kept in database, so flags stay valid after restart
+	_, err = steward.GetLastFlagKey(db)
+	if err == sql.ErrNoRows {
+		err = addFlagKey(db)
+	}
 	if err != nil {
-		log.Fatalln("Generate key fail:", err)
+		log.Fatalln("Get flag key fail:", err)
 	}
 
 	attackFlow := make(chan scoreboard.Attack, config.API.AttackBuffer)
 
-	go receiver.FlagReceiver(db, priv, config.FlagReceiver.Addr,
-		config.FlagReceiver.ReceiveTimeout.Duration,
-		config.FlagReceiver.SocketTimeout.Duration,
+	// Budget of team is shared between tcp and http receivers
+	limiter := receiver.NewRateLimiter(config.FlagReceiver.FlagsPerSecond)
+
+	receiver.SetFlagLifetime(config.FlagReceiver.FlagLifetime)
+
+	go receiver.FlagReceiver(db, config.FlagReceiver.Addr,
+		config.FlagReceiver.SocketTimeout.Duration, limiter,
 		attackFlow)
 
+	if config.FlagReceiver.HTTPAddr != "" {
+		go receiver.HTTPFlagReceiver(db, config.FlagReceiver.HTTPAddr,
+			limiter, attackFlow)
+	}
+
 	go receiver.AdvisoryReceiver(db, config.AdvisoryReceiver.Addr,
 		config.AdvisoryReceiver.ReceiveTimeout.Duration,
 		config.AdvisoryReceiver.SocketTimeout.Duration)
@@ -170,15 +292,11 @@ func main() {
 		config.Scoreboard.WwwPath,
 		config.Scoreboard.Addr,
 		config.Scoreboard.UpdateTimeout.Duration,
-		config.Pulse.Start.Time,
-		config.Pulse.Half.Duration,
-		config.Pulse.Lunch.Duration,
-		config.Pulse.DarkestTime.Duration)
-
-	err = pulse.Pulse(db, priv,
-		config.Pulse.Start.Time,
-		config.Pulse.Half.Duration,
-		config.Pulse.Lunch.Duration,
+		sched,
+		config.Pulse.DarkestTime.Duration,
+		config.FlagReceiver.FlagLifetime)
+
+	err = pulse.Pulse(db, sched,
 		config.Pulse.RoundLen.Duration,
 		config.Pulse.CheckTimeout.Duration)
 	if err != nil {
@@ -9,7 +9,6 @@
 package pulse
 
 import (
-	"crypto/rsa"
 	"database/sql"
 	"log"
 	"math/rand"
@@ -48,7 +47,6 @@ func RandomizeTimeout(duration, deviation time.Duration) (ret time.Duration) {
 // Game contains game info
 type Game struct {
 	db       *sql.DB
-	priv     *rsa.PrivateKey
 	roundLen time.Duration
 	timeout  time.Duration
 	teams    []steward.Team
@@ -56,10 +54,9 @@ type Game struct {
 }
 
 // NewGame create new Game object
-func NewGame(db *sql.DB, priv *rsa.PrivateKey, roundLen time.Duration,
+func NewGame(db *sql.DB, roundLen time.Duration,
 	timeout time.Duration) (g Game, err error) {
 
-	g.priv = priv
 	g.roundLen = roundLen
 	g.timeout = timeout
 	g.db = db
@@ -117,7 +114,14 @@ func (g Game) Round(counters *sync.WaitGroup) (err error) {
 
 	log.Println("New round", roundNo)
 
-	err = checker.PutFlags(g.db, g.priv, roundNo, g.teams, g.services)
+	// Last key is read on each round, so rotated key is used without
+	// restart
+	key, err := steward.GetLastFlagKey(g.db)
+	if err != nil {
+		return
+	}
+
+	err = checker.PutFlags(g.db, key.Key, roundNo, g.teams, g.services)
 	if err != nil {
 		return
 	}
@@ -51,6 +51,7 @@ func (t testDB) Close() {
 	t.db.Exec("DROP TABLE status")
 	t.db.Exec("DROP TABLE round")
 	t.db.Exec("DROP TABLE round_result")
+	t.db.Exec("DROP TABLE service_result")
 
 	t.db.Close()
 }
@@ -120,11 +121,16 @@ func TestGame(*testing.T) {
 
 	defer svc.Stop()
 
-	priv, err := vexillary.GenerateKey()
+	key, err := vexillary.GenerateKey()
 	if err != nil {
 		log.Fatalln("Generate key fail:", err)
 	}
 
+	_, err = steward.AddFlagKey(db.db, key)
+	if err != nil {
+		log.Fatalln("Add key fail:", err)
+	}
+
 	for index, team := range []string{"FooTeam", "BarTeam", "BazTeam"} {
 
 		// just trick for bypass UNIQUE team subnet
@@ -156,7 +162,7 @@ func TestGame(*testing.T) {
 	round_len := 30 * time.Second
 	timeout_between_check := 10 * time.Second
 
-	game, err := pulse.NewGame(db.db, priv, round_len, timeout_between_check)
+	game, err := pulse.NewGame(db.db, round_len, timeout_between_check)
 
 	defer game.Over()
 
@@ -11,12 +11,13 @@
 package pulse
 
 import (
-	"crypto/rsa"
 	"database/sql"
 	"log"
 	"time"
 )
 
+import "github.com/jollheef/tin_foil_hat/schedule"
+
 // Wait for time
 func Wait(end time.Time, timeout time.Duration) (waited bool) {
 
@@ -31,48 +32,48 @@ func Wait(end time.Time, timeout time.Duration) (waited bool) {
 	return true
 }
 
-// Pulse manage game
-func Pulse(db *sql.DB, priv *rsa.PrivateKey, startTime time.Time,
-	half, lunch, roundLen, checkTimeout time.Duration) (err error) {
+// Pulse manage game, rounds are generated only in running periods
+func Pulse(db *sql.DB, sched schedule.Schedule,
+	roundLen, checkTimeout time.Duration) (err error) {
 
 	log.Println("Launching pulse...")
 
-	lunchStartTime := startTime.Add(half)
-	lunchEndTime := lunchStartTime.Add(lunch)
-	endTime := lunchEndTime.Add(half)
-
 	log.Println("Pulse start time", time.Now())
 
-	log.Println("Contest start time", startTime)
+	log.Println("Contest start time", sched.Start)
 
-	game, err := NewGame(db, priv, roundLen, checkTimeout)
+	game, err := NewGame(db, roundLen, checkTimeout)
+	if err != nil {
+		return
+	}
 
 	defer game.Over()
 
 	timeout := 100 * time.Millisecond
 
 	log.Println("Wait start time...")
-	if Wait(startTime, timeout) || time.Now().Before(lunchStartTime) {
-		log.Println("game run")
-		err = game.Run(lunchStartTime)
-		if err != nil {
-			return
+	for _, p := range sched.Periods {
+
+		if !p.Running {
+			log.Println("Pause", p.Name, "until", p.End)
+			Wait(p.End, timeout)
+			continue
 		}
-	}
 
-	log.Println("Wait lunch time")
-	Wait(lunchStartTime, timeout)
+		Wait(p.Start, timeout)
 
-	log.Println("Lunch...")
-	if Wait(lunchEndTime, timeout) || time.Now().Before(endTime) {
-		err = game.Run(endTime)
-		if err != nil {
-			return
+		// Period can be already over if pulse restarted
+		if time.Now().Before(p.End) {
+			log.Println("Period", p.Name, "run")
+			err = game.Run(p.End)
+			if err != nil {
+				return
+			}
 		}
 	}
 
 	log.Println("Wait end time")
-	Wait(endTime, timeout)
+	Wait(sched.End(), timeout)
 
 	return
 }
@@ -0,0 +1,80 @@
+/**
+ * @file cache.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief cache of data from database
+ *
+ * Flag keys and teams are rarely changed during the game, so they are
+ * cached and reloaded from database periodically, and also on miss (e.g.
+ * flag is signed by key added after last reload), but not more often than
+ * once per cacheMissRefresh.
+ */
+
+package receiver
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+const (
+	cacheRefresh     = 10 * time.Second // reload cached value
+	cacheMissRefresh = time.Second      // min interval of reload on miss
+)
+
+type cache[T any] struct {
+	mutex   sync.Mutex
+	load    func(db *sql.DB) (T, error)
+	db      *sql.DB
+	value   T
+	updated time.Time
+}
+
+func (c *cache[T]) reloadLocked(db *sql.DB) (err error) {
+
+	value, err := c.load(db)
+	if err != nil {
+		return
+	}
+
+	c.db = db
+	c.value = value
+	c.updated = time.Now()
+
+	return
+}
+
+// reload value from database
+func (c *cache[T]) reload(db *sql.DB) (err error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.reloadLocked(db)
+}
+
+// get returns cached value, value is reloaded if outdated or if miss is
+// set, reloaded is true if value is reloaded by this call
+func (c *cache[T]) get(db *sql.DB, miss bool) (value T, reloaded bool,
+	err error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.db != db || time.Since(c.updated) > cacheRefresh ||
+		(miss && time.Since(c.updated) > cacheMissRefresh) {
+
+		err = c.reloadLocked(db)
+		if err != nil {
+			return
+		}
+
+		reloaded = true
+	}
+
+	value = c.value
+
+	return
+}
@@ -0,0 +1,125 @@
+/**
+ * @file http.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief routine for receive flags over http
+ *
+ * Provide http server for receive flags. Team send json array of flags with
+ * own token in X-Team-Token header, and get verdict for each flag.
+ */
+
+package receiver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+import (
+	"github.com/jollheef/tin_foil_hat/scoreboard"
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+const (
+	maxFlagsPerRequest = 1000
+	maxRequestSize     = 1 << 20
+)
+
+// FlagVerdict contains result of flag submission
+type FlagVerdict struct {
+	Flag    string
+	Verdict string
+}
+
+func httpFlagHandler(w http.ResponseWriter, r *http.Request, db *sql.DB,
+	limiter *RateLimiter, attackFlow chan scoreboard.Attack) {
+
+	if r.Method != "POST" {
+		http.Error(w, "Use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	team, err := steward.GetTeamByToken(db, r.Header.Get("X-Team-Token"))
+	if err != nil {
+		log.Println("\tGet team by token failed:", err)
+		http.Error(w, strings.Trim(invalidTeamMsg, "\n"),
+			http.StatusForbidden)
+		return
+	}
+
+	var flags []string
+
+	body := http.MaxBytesReader(w, r.Body, maxRequestSize)
+
+	err = json.NewDecoder(body).Decode(&flags)
+	if err != nil {
+		http.Error(w, "Expected json array of flags",
+			http.StatusBadRequest)
+		return
+	}
+
+	if len(flags) > maxFlagsPerRequest {
+		http.Error(w, "Too many flags", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	verdicts := []FlagVerdict{}
+
+	for _, flag := range flags {
+
+		log.Printf("\tGet flag %s from %s (%s)", flag, team.Name,
+			r.RemoteAddr)
+
+		var msg string
+		if limiter.Allow(team.ID) {
+			msg = captureFlag(db, team, flag, attackFlow)
+		} else {
+			msg = attemptsLimitMsg
+		}
+
+		logSubmission(db, team.ID, r.RemoteAddr, flag, msg)
+
+		verdicts = append(verdicts, FlagVerdict{flag,
+			strings.Trim(msg, "\n")})
+	}
+
+	buf, err := json.Marshal(verdicts)
+	if err != nil {
+		log.Println("Serialization error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_, err = w.Write(buf)
+	if err != nil {
+		log.Println("Verdicts write error:", err)
+		return
+	}
+}
+
+// HTTPFlagReceiver starts http flag receiver
+func HTTPFlagReceiver(db *sql.DB, addr string, limiter *RateLimiter,
+	attackFlow chan scoreboard.Attack) (err error) {
+
+	log.Println("Launching http receiver at", addr, "...")
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/flags",
+		func(w http.ResponseWriter, r *http.Request) {
+			httpFlagHandler(w, r, db, limiter, attackFlow)
+		})
+
+	err = http.ListenAndServe(addr, mux)
+	if err != nil {
+		log.Println("Http receiver fail:", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,163 @@
+/**
+ * @file http_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test http flag receiver
+ */
+
+package receiver
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/jollheef/tin_foil_hat/scoreboard"
+	"github.com/jollheef/tin_foil_hat/steward"
+	"github.com/jollheef/tin_foil_hat/vexillary"
+)
+
+func submitFlags(url, token string, flags []string) (code int,
+	verdicts []FlagVerdict) {
+
+	buf, err := json.Marshal(flags)
+	if err != nil {
+		log.Fatalln("Serialization error:", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(buf))
+	if err != nil {
+		log.Fatalln("Create request failed:", err)
+	}
+
+	req.Header.Set("X-Team-Token", token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatalln("Submit flags failed:", err)
+	}
+
+	defer resp.Body.Close()
+
+	code = resp.StatusCode
+
+	if code == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(&verdicts)
+		if err != nil {
+			log.Fatalln("Invalid response:", err)
+		}
+	}
+
+	return
+}
+
+func TestHTTPFlagReceiver(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	key, err := vexillary.GenerateKey()
+	if err != nil {
+		log.Fatalln("Generate key failed:", err)
+	}
+
+	_, err = steward.AddFlagKey(db.db, key)
+	if err != nil {
+		log.Fatalln("Add key failed:", err)
+	}
+
+	attackFlow := make(chan scoreboard.Attack, 10)
+
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			httpFlagHandler(w, r, db.db, NewRateLimiter(0),
+				attackFlow)
+		}))
+
+	defer ts.Close()
+
+	round, err := steward.NewRound(db.db, time.Minute)
+	if err != nil {
+		log.Fatalln("New round failed:", err)
+	}
+
+	t := steward.Team{ID: -1, Name: "TestTeam", Subnet: "127.0.0.1/24",
+		Vulnbox: "1", Token: "secret"}
+
+	teamID, err := steward.AddTeam(db.db, t)
+	if err != nil {
+		log.Fatalln("Add team failed:", err)
+	}
+
+	serviceID := 1
+
+	steward.PutStatus(db.db, steward.Status{Round: round, TeamID: teamID,
+		ServiceID: serviceID, State: steward.StatusUP})
+
+	flag, err := vexillary.GenerateFlag(key, round, 8, serviceID)
+	if err != nil {
+		log.Fatalln("Generate flag failed:", err)
+	}
+
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag,
+		Round: round, TeamID: 8, ServiceID: serviceID})
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	// Team must be authenticated by token
+	code, _ := submitFlags(ts.URL, "invalid", []string{flag})
+	if code != http.StatusForbidden {
+		log.Fatalln("Invalid token accepted, code", code)
+	}
+
+	flags := []string{flag, flag, "1e7b642f2282886377d1655af6097dd6101eac5b="}
+
+	expected := []string{capturedMsg, alreadyCapturedMsg, invalidFlagMsg}
+
+	code, verdicts := submitFlags(ts.URL, t.Token, flags)
+	if code != http.StatusOK {
+		log.Fatalln("Submit flags failed, code", code)
+	}
+
+	if len(verdicts) != len(flags) {
+		log.Fatalln("Get", len(verdicts), "verdicts instead", len(flags))
+	}
+
+	for i, v := range verdicts {
+		if v.Flag != flags[i] || v.Verdict != strings.Trim(expected[i], "\n") {
+			log.Fatalf("Invalid verdict [%v] instead [%v]", v.Verdict,
+				expected[i])
+		}
+	}
+
+	// Same flag can be captured by other team
+	t2 := steward.Team{ID: -1, Name: "OtherTeam", Subnet: "127.0.1.1/24",
+		Vulnbox: "2", Token: "other_secret"}
+
+	team2ID, err := steward.AddTeam(db.db, t2)
+	if err != nil {
+		log.Fatalln("Add team failed:", err)
+	}
+
+	steward.PutStatus(db.db, steward.Status{Round: round, TeamID: team2ID,
+		ServiceID: serviceID, State: steward.StatusUP})
+
+	_, verdicts = submitFlags(ts.URL, t2.Token, []string{flag})
+	if len(verdicts) != 1 ||
+		verdicts[0].Verdict != strings.Trim(capturedMsg, "\n") {
+		log.Fatalln("Flag is not captured by other team:", verdicts)
+	}
+}
@@ -0,0 +1,40 @@
+/**
+ * @file key.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief cache of flag keys
+ *
+ * Keys are cached (see cache.go). Key added by `tfhctl key rotate` signs
+ * flags from next round, and keys are also reloaded when flag is not signed
+ * by any cached key, so it is valid in receiver before first flag signed
+ * by it is put.
+ */
+
+package receiver
+
+import (
+	"database/sql"
+)
+
+import (
+	"github.com/jollheef/tin_foil_hat/steward"
+	"github.com/jollheef/tin_foil_hat/vexillary"
+)
+
+var flagKeys = cache[[]vexillary.Key]{load: loadFlagKeys}
+
+// loadFlagKeys returns all keys of game
+func loadFlagKeys(db *sql.DB) (keys []vexillary.Key, err error) {
+
+	list, err := steward.GetFlagKeys(db)
+	if err != nil {
+		return
+	}
+
+	for _, key := range list {
+		keys = append(keys, key.Key)
+	}
+
+	return
+}
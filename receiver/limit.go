@@ -0,0 +1,73 @@
+/**
+ * @file limit.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief per-team submission rate limit
+ *
+ * Provide token bucket limiter, which allow each team submit not more than
+ * N flags per second regardless of connections count. Budget is shared
+ * between tcp and http receivers.
+ */
+
+package receiver
+
+import (
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter limits submissions of each team, one limiter must be shared
+// between receivers
+type RateLimiter struct {
+	rate    float64 // tokens per second, also bucket capacity
+	buckets map[int]*bucket
+	mutex   sync.Mutex
+}
+
+// NewRateLimiter returns limiter which allow each team submit not more than
+// flagsPerSecond flags per second, zero means unlimited
+func NewRateLimiter(flagsPerSecond int) *RateLimiter {
+	return &RateLimiter{
+		rate:    float64(flagsPerSecond),
+		buckets: make(map[int]*bucket),
+	}
+}
+
+// Allow returns false if team exceeded flags per second budget
+func (l *RateLimiter) Allow(teamID int) bool {
+
+	if l.rate <= 0 { // no limit
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+
+	b, ok := l.buckets[teamID]
+	if !ok {
+		b = &bucket{tokens: l.rate, last: now}
+		l.buckets[teamID] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.rate {
+		b.tokens = l.rate
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
@@ -0,0 +1,52 @@
+/**
+ * @file limit_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test per-team submission rate limit
+ */
+
+package receiver
+
+import (
+	"log"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(*testing.T) {
+
+	limiter := NewRateLimiter(5)
+
+	for i := 0; i < 5; i++ {
+		if !limiter.Allow(1) {
+			log.Fatalln("Flag", i, "rejected within budget")
+		}
+	}
+
+	if limiter.Allow(1) {
+		log.Fatalln("Flag accepted over budget")
+	}
+
+	// Budget of other team is not affected
+	if !limiter.Allow(2) {
+		log.Fatalln("Other team flag rejected")
+	}
+
+	time.Sleep(time.Second / 2)
+
+	if !limiter.Allow(1) {
+		log.Fatalln("Budget is not restored")
+	}
+}
+
+func TestRateLimiterUnlimited(*testing.T) {
+
+	limiter := NewRateLimiter(0)
+
+	for i := 0; i < 1000; i++ {
+		if !limiter.Allow(1) {
+			log.Fatalln("Unlimited limiter reject flag")
+		}
+	}
+}
@@ -5,18 +5,18 @@
  * @date September, 2015
  * @brief routine for receive flags from commands
  *
- * Provide tcp server for receive flags. After receive flag daemon perform
- * validate flag, check flag round and write result to db.
+ * Provide tcp server for receive flags. Team can send many flags (one per
+ * line) in one session. After receive flag daemon perform validate flag,
+ * check flag round and write result to db.
  */
 
 package receiver
 
 import (
 	"bufio"
-	"crypto/rsa"
 	"database/sql"
-	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"strings"
@@ -43,149 +43,131 @@ const (
 	serviceNotUpMsg     string = "The attacking team service is not up\n"
 )
 
-func parseAddr(addr string) (subnetNo int, err error) {
+var flagLifetime = 1 // in rounds
 
-	defer func() {
-		if r := recover(); r != nil {
-			err = errors.New("Cannot parse '" + addr + "'")
-		}
-	}()
-	_, err = fmt.Sscanf(strings.Split(addr, ".")[2], "%d", &subnetNo)
-
-	return
+// SetFlagLifetime set count of rounds while flag can be captured, one means
+// only flags from current round
+func SetFlagLifetime(rounds int) {
+	flagLifetime = rounds
 }
 
-func teamByAddr(db *sql.DB, addr string) (team steward.Team, err error) {
+const maxSubmissionFlagLen = 256
 
-	subnetNo, err := parseAddr(addr)
+// logSubmission keep submission attempt with verdict, team id is zero for
+// unknown team
+func logSubmission(db *sql.DB, teamID int, addr, flag, msg string) {
+
+	host, _, err := net.SplitHostPort(addr)
 	if err != nil {
-		return
+		host = addr
 	}
 
-	teams, err := steward.GetTeams(db)
+	// Flag is sent by team, so it can be any bytes
+	flag = steward.TruncateText(steward.ValidText(flag),
+		maxSubmissionFlagLen)
+
+	err = steward.AddSubmission(db, steward.Submission{TeamID: teamID,
+		Addr: host, Flag: flag, Verdict: strings.Trim(msg, "\n"),
+		Accepted: msg == capturedMsg})
 	if err != nil {
-		return
+		log.Println("\tAdd submission failed:", err)
 	}
+}
 
-	for i := 0; i < len(teams); i++ {
+func captureFlag(db *sql.DB, team steward.Team, flag string,
+	attackFlow chan scoreboard.Attack) string {
 
-		team = teams[i]
+	keys, _, err := flagKeys.get(db, false)
+	if err != nil {
+		log.Println("\tGet flag keys failed:", err)
+		return internalErrorMsg
+	}
 
-		teamSubnetNo, err := parseAddr(team.Subnet)
-		if err != nil {
-			return team, err
+	info, err := vexillary.ParseFlag(flag, keys...)
+	if err != nil {
+		// Flag can be signed by key rotated after last reload
+		keys, reloaded, e := flagKeys.get(db, true)
+		if e != nil {
+			log.Println("\tGet flag keys failed:", e)
+			return internalErrorMsg
 		}
 
-		if teamSubnetNo == subnetNo {
-			return team, err
+		if reloaded {
+			info, err = vexillary.ParseFlag(flag, keys...)
 		}
 	}
 
-	err = errors.New("team not found")
-
-	return
-}
-
-func handler(conn net.Conn, db *sql.DB, priv *rsa.PrivateKey,
-	attackFlow chan scoreboard.Attack) {
-
-	addr := conn.RemoteAddr().String()
-
-	defer conn.Close()
-
-	fmt.Fprint(conn, greetingMsg)
-
-	flag, err := bufio.NewReader(conn).ReadString('\n')
 	if err != nil {
-		log.Println("Read error:", err)
+		log.Println("\tValidate flag failed:", err)
+		return invalidFlagMsg
 	}
 
-	flag = strings.Trim(flag, "\n")
-
-	log.Printf("\tGet flag %s from %s", flag, addr)
+	// Flag metadata is signed, so it is checked before database lookup
+	if info.TeamID == team.ID {
+		log.Printf("\tTeam %s try to send their flag", team.Name)
+		return flagYoursMsg
+	}
 
-	valid, err := vexillary.ValidFlag(flag, priv.PublicKey)
+	round, err := steward.CurrentRound(db)
 	if err != nil {
-		log.Println("\tValidate flag failed:", err)
+		log.Println("\tGet current round failed:", err)
+		return internalErrorMsg
 	}
-	if !valid {
-		fmt.Fprint(conn, invalidFlagMsg)
-		return
+
+	if round.ID-info.Round >= flagLifetime {
+		log.Printf("\t%s try to send expired flag from round %d",
+			team.Name, info.Round)
+		return flagExpiredMsg
 	}
 
 	exist, err := steward.FlagExist(db, flag)
 	if err != nil {
 		log.Println("\tExist flag check failed:", err)
-		fmt.Fprint(conn, internalErrorMsg)
-		return
+		return internalErrorMsg
 	}
 	if !exist {
-		fmt.Fprint(conn, flagDoesNotExistMsg)
-		return
+		return flagDoesNotExistMsg
 	}
 
 	flg, err := steward.GetFlagInfo(db, flag)
 	if err != nil {
 		log.Println("\tGet flag info failed:", err)
-		fmt.Fprint(conn, internalErrorMsg)
-		return
+		return internalErrorMsg
 	}
 
-	captured, err := steward.AlreadyCaptured(db, flg.ID)
+	captured, err := steward.AlreadyCaptured(db, flg.ID, team.ID)
 	if err != nil {
 		log.Println("\tAlready captured check failed:", err)
-		fmt.Fprint(conn, internalErrorMsg)
-		return
+		return internalErrorMsg
 	}
 	if captured {
-		fmt.Fprint(conn, alreadyCapturedMsg)
-		return
-	}
-
-	team, err := teamByAddr(db, addr)
-	if err != nil {
-		log.Println("\tGet team by ip failed:", err)
-		fmt.Fprint(conn, invalidTeamMsg)
-		return
-	}
-
-	if flg.TeamID == team.ID {
-		log.Printf("\tTeam %s try to send their flag", team.Name)
-		fmt.Fprint(conn, flagYoursMsg)
-		return
-	}
-
-	round, err := steward.CurrentRound(db)
-
-	if round.ID != flg.Round {
-		log.Printf("\t%s try to send flag from past round", team.Name)
-		fmt.Fprint(conn, flagExpiredMsg)
-		return
+		return alreadyCapturedMsg
 	}
 
 	roundEndTime := round.StartTime.Add(round.Len)
 
 	if time.Now().After(roundEndTime) {
 		log.Printf("\t%s try to send flag from finished round", team.Name)
-		fmt.Fprint(conn, flagExpiredMsg)
-		return
+		return flagExpiredMsg
 	}
 
-	halfStatus := steward.Status{flg.Round, team.ID, flg.ServiceID,
-		steward.StatusUnknown}
+	halfStatus := steward.Status{Round: round.ID, TeamID: team.ID,
+		ServiceID: flg.ServiceID, State: steward.StatusUnknown}
 	state, err := steward.GetState(db, halfStatus)
 
 	if state != steward.StatusUP {
 		log.Printf("\t%s service not ok, cannot capture", team.Name)
-		fmt.Fprint(conn, serviceNotUpMsg)
-		return
+		return serviceNotUpMsg
 	}
 
-	err = steward.CaptureFlag(db, flg.ID, team.ID)
+	err = steward.CaptureFlag(db, flg.ID, team.ID, round.ID)
+	if err == steward.ErrAlreadyCaptured {
+		// Flag is sent twice at once
+		return alreadyCapturedMsg
+	}
 	if err != nil {
 		log.Println("\tCapture flag failed:", err)
-		fmt.Fprint(conn, internalErrorMsg)
-		return
+		return internalErrorMsg
 	}
 
 	go func() {
@@ -204,43 +186,85 @@ func handler(conn net.Conn, db *sql.DB, priv *rsa.PrivateKey,
 		}
 	}()
 
-	fmt.Fprint(conn, capturedMsg)
+	return capturedMsg
 }
 
-// FlagReceiver starts flag receiver
-func FlagReceiver(db *sql.DB, priv *rsa.PrivateKey, addr string,
-	timeout, socketTimeout time.Duration,
-	attackFlow chan scoreboard.Attack) {
+// handler serve session, team can send one flag per line and get verdict
+// for each of them
+func handler(conn net.Conn, db *sql.DB, socketTimeout time.Duration,
+	limiter *RateLimiter, attackFlow chan scoreboard.Attack) {
 
-	log.Println("Launching receiver at", addr, "...")
+	addr := conn.RemoteAddr().String()
 
-	connects := make(map[string]time.Time) // { ip : last_connect_time }
+	defer conn.Close()
 
-	listener, _ := net.Listen("tcp", addr)
+	fmt.Fprint(conn, greetingMsg)
+
+	team, teamErr := teamByAddr(db, addr)
+
+	reader := bufio.NewReader(conn)
 
 	for {
-		conn, _ := listener.Accept()
+		flag, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			log.Println("Read error:", err)
+		}
 
-		addr := conn.RemoteAddr().String()
+		flag = strings.Trim(flag, "\n")
 
-		log.Printf("Connection accepted from %s", addr)
+		if flag != "" {
+			log.Printf("\tGet flag %s from %s", flag, addr)
 
-		ip, _, err := net.SplitHostPort(addr)
-		if err != nil {
-			log.Println("\tCannot split remote addr:", err)
-			fmt.Fprint(conn, internalErrorMsg)
-			conn.Close()
-			continue
+			var msg string
+			if teamErr != nil {
+				log.Println("\tGet team by ip failed:", teamErr)
+				msg = invalidTeamMsg
+			} else if !limiter.Allow(team.ID) {
+				log.Printf("\tToo fast submits by %s", team.Name)
+				msg = attemptsLimitMsg
+			} else {
+				msg = captureFlag(db, team, flag, attackFlow)
+			}
+
+			fmt.Fprint(conn, msg)
+
+			logSubmission(db, team.ID, addr, flag, msg)
+
+			// Submissions of unknown team are not rate limited,
+			// so only one of them is kept per session
+			if teamErr != nil {
+				return
+			}
 		}
 
-		if time.Now().Before(connects[ip].Add(timeout)) {
-			log.Println("\tToo fast connects by", ip)
-			fmt.Fprint(conn, attemptsLimitMsg)
-			conn.Close()
-			continue
+		if err != nil {
+			return
 		}
 
 		err = conn.SetDeadline(time.Now().Add(socketTimeout))
+		if err != nil {
+			log.Println("Set deadline fail:", err)
+			return
+		}
+	}
+}
+
+// FlagReceiver starts flag receiver
+func FlagReceiver(db *sql.DB, addr string, socketTimeout time.Duration,
+	limiter *RateLimiter, attackFlow chan scoreboard.Attack) {
+
+	log.Println("Launching receiver at", addr, "...")
+
+	listener, _ := net.Listen("tcp", addr)
+
+	for {
+		conn, _ := listener.Accept()
+
+		addr := conn.RemoteAddr().String()
+
+		log.Printf("Connection accepted from %s", addr)
+
+		err := conn.SetDeadline(time.Now().Add(socketTimeout))
 		if err != nil {
 			log.Println("Set deadline fail:", err)
 			fmt.Fprint(conn, internalErrorMsg)
@@ -248,8 +272,6 @@ func FlagReceiver(db *sql.DB, priv *rsa.PrivateKey, addr string,
 			continue
 		}
 
-		go handler(conn, db, priv, attackFlow)
-
-		connects[ip] = time.Now()
+		go handler(conn, db, socketTimeout, limiter, attackFlow)
 	}
 }
@@ -12,11 +12,13 @@ import (
 	"bufio"
 	"database/sql"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 )
 
 import (
@@ -51,22 +53,6 @@ func (t testDB) Close() {
 	t.db.Close()
 }
 
-func TestparseAddr(t *testing.T) {
-
-	for id := 0; id < 255; id++ {
-		addr := fmt.Sprintf("127.0.%d.1:44259", id)
-
-		teamID, err := parseAddr(addr)
-		if err != nil {
-			log.Fatalln("Parse addr failed:", err)
-		}
-
-		if teamID != id {
-			log.Fatalf("Parsed [%v] instead [%v]", teamID, id)
-		}
-	}
-}
-
 func TestteamByAddr(*testing.T) {
 
 	db, err := openDB()
@@ -90,6 +76,11 @@ func TestteamByAddr(*testing.T) {
 			log.Fatalln("Add team failed:", err)
 		}
 
+		err = teams.reload(db.db)
+		if err != nil {
+			log.Fatalln("Refresh teams failed:", err)
+		}
+
 		addr := fmt.Sprintf("127.0.%d.115:3542", i)
 
 		team, err := teamByAddr(db.db, addr)
@@ -102,6 +93,24 @@ func TestteamByAddr(*testing.T) {
 				team.ID, teamID)
 		}
 	}
+
+	t := steward.Team{ID: -1, Name: "Team_v6", Subnet: "fd00:0:0:20::/64",
+		Vulnbox: "fd00:0:0:20::3"}
+
+	teamID, err := steward.AddTeam(db.db, t)
+	if err != nil {
+		log.Fatalln("Add team failed:", err)
+	}
+
+	err = teams.reload(db.db)
+	if err != nil {
+		log.Fatalln("Refresh teams failed:", err)
+	}
+
+	team, err := teamByAddr(db.db, "[fd00:0:0:20::115]:3542")
+	if err != nil || team.ID != teamID {
+		log.Fatalln("Get IPv6 team failed:", team, err)
+	}
 }
 
 func testFlag(addr, flag, response string) {
@@ -145,19 +154,25 @@ func TestReceiver(*testing.T) {
 
 	defer db.Close()
 
-	priv, err := vexillary.GenerateKey()
+	key, err := vexillary.GenerateKey()
 	if err != nil {
 		log.Fatalln("Generate key failed:", err)
 	}
 
+	_, err = steward.AddFlagKey(db.db, key)
+	if err != nil {
+		log.Fatalln("Add key failed:", err)
+	}
+
 	addr := "127.0.0.1:65000"
 
-	flag, err := vexillary.GenerateFlag(priv)
+	flag, err := vexillary.GenerateFlag(key, 1, 8, 1)
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
 
-	err = steward.AddFlag(db.db, steward.Flag{-1, flag, 1, 8, 1, ""})
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag,
+		Round: 1, TeamID: 8, ServiceID: 1, Cred: ""})
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
@@ -169,13 +184,30 @@ func TestReceiver(*testing.T) {
 
 	attackFlow := make(chan scoreboard.Attack)
 
-	go FlagReceiver(db.db, priv, addr, time.Nanosecond, time.Minute, attackFlow)
+	go FlagReceiver(db.db, addr, time.Minute, NewRateLimiter(0),
+		attackFlow)
 
 	time.Sleep(time.Second) // wait for init listener
 
 	// The attacker must appear to be a team (e.g. jury cannot attack)
 	testFlag(addr, flag, invalidTeamMsg)
 
+	// Session of unknown team is closed after first flag
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		log.Fatalln("Connect to receiver failed:", err)
+	}
+
+	fmt.Fprint(conn, flag+"\n")
+
+	reply, err := ioutil.ReadAll(conn)
+	if err != nil || string(reply) != greetingMsg+invalidTeamMsg {
+		log.Fatalf("Session of unknown team is not closed: [%s] %v",
+			reply, err)
+	}
+
+	conn.Close()
+
 	t := steward.Team{ID: -1, Name: "TestTeam", Subnet: "127.0.0.1/24",
 		Vulnbox: "1"}
 
@@ -185,11 +217,16 @@ func TestReceiver(*testing.T) {
 		log.Fatalln("Add team failed:", err)
 	}
 
+	err = teams.reload(db.db)
+	if err != nil {
+		log.Fatalln("Refresh teams failed:", err)
+	}
+
 	serviceID := 1
 
 	// Flag must be captured only if service status ok
-	steward.PutStatus(db.db, steward.Status{firstRound, teamID, serviceID,
-		steward.StatusUP})
+	steward.PutStatus(db.db, steward.Status{Round: firstRound,
+		TeamID: teamID, ServiceID: serviceID, State: steward.StatusUP})
 
 	testFlag(addr, flag, capturedMsg)
 
@@ -201,7 +238,7 @@ func TestReceiver(*testing.T) {
 		invalidFlagMsg)
 
 	// Correct flag that does not exist in database must not be captured
-	newFlag, err := vexillary.GenerateFlag(priv)
+	newFlag, err := vexillary.GenerateFlag(key, 1, 8, 1)
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
@@ -209,12 +246,13 @@ func TestReceiver(*testing.T) {
 	testFlag(addr, newFlag, flagDoesNotExistMsg)
 
 	// Submitted flag does not belongs to the attacking team
-	flag4, err := vexillary.GenerateFlag(priv)
+	flag4, err := vexillary.GenerateFlag(key, 1, teamID, 1)
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
 
-	err = steward.AddFlag(db.db, steward.Flag{-1, flag4, 1, teamID, 1, ""})
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag4,
+		Round: 1, TeamID: teamID, ServiceID: 1, Cred: ""})
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
@@ -222,14 +260,17 @@ func TestReceiver(*testing.T) {
 	testFlag(addr, flag4, flagYoursMsg)
 
 	// Correct flag from another round must not be captured
-	flag2, err := vexillary.GenerateFlag(priv)
+	curRound, err := steward.CurrentRound(db.db)
+
+	flag2, err := vexillary.GenerateFlag(key, curRound.ID, 8, 1)
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
 
-	curRound, err := steward.CurrentRound(db.db)
-
-	err = steward.AddFlag(db.db, steward.Flag{-1, flag2, curRound.ID, 8, 1, ""})
+	// Other flag store, first flag of team is from same round
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag2,
+		Round: curRound.ID, TeamID: 8, ServiceID: 1, Cred: "",
+		Vuln: 2})
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
@@ -241,6 +282,18 @@ func TestReceiver(*testing.T) {
 
 	testFlag(addr, flag2, flagExpiredMsg)
 
+	// Flag from previous round can be captured if lifetime allows
+	SetFlagLifetime(2)
+
+	curRound, err = steward.CurrentRound(db.db)
+
+	steward.PutStatus(db.db, steward.Status{Round: curRound.ID,
+		TeamID: teamID, ServiceID: serviceID, State: steward.StatusUP})
+
+	testFlag(addr, flag2, capturedMsg)
+
+	SetFlagLifetime(1)
+
 	// Correct flag from expired round must not be captured
 	roundLen := time.Second
 	roundID, err := steward.NewRound(db.db, roundLen)
@@ -248,12 +301,13 @@ func TestReceiver(*testing.T) {
 		log.Fatalln("New round failed:", err)
 	}
 
-	flag3, err := vexillary.GenerateFlag(priv)
+	flag3, err := vexillary.GenerateFlag(key, roundID, 8, 1)
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
 
-	err = steward.AddFlag(db.db, steward.Flag{-1, flag3, roundID, 8, 1, ""})
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag3,
+		Round: roundID, TeamID: 8, ServiceID: 1, Cred: ""})
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
@@ -268,53 +322,136 @@ func TestReceiver(*testing.T) {
 		log.Fatalln("New round failed:", err)
 	}
 
-	flag5, err := vexillary.GenerateFlag(priv)
+	flag5, err := vexillary.GenerateFlag(key, roundID, 8,
+		serviceID)
 	if err != nil {
 		log.Fatalln("Generate flag failed:", err)
 	}
 
-	err = steward.AddFlag(db.db, steward.Flag{-1, flag5, roundID, 8,
-		serviceID, ""})
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: flag5,
+		Round: roundID, TeamID: 8, ServiceID: serviceID, Cred: ""})
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
 
-	steward.PutStatus(db.db, steward.Status{roundID, teamID, serviceID,
-		steward.StatusDown})
+	steward.PutStatus(db.db, steward.Status{Round: roundID,
+		TeamID: teamID, ServiceID: serviceID, State: steward.StatusDown})
 
 	testFlag(addr, flag5, serviceNotUpMsg)
 
-	steward.PutStatus(db.db, steward.Status{roundID, teamID, serviceID,
-		steward.StatusUP})
+	steward.PutStatus(db.db, steward.Status{Round: roundID,
+		TeamID: teamID, ServiceID: serviceID, State: steward.StatusUP})
+
+	// Several flags can be sent in one session
+	conn, err = net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		log.Fatalln("Connect to receiver failed:", err)
+	}
+
+	reader := bufio.NewReader(conn)
+
+	_, err = reader.ReadString('\n') // skip greeting
+	if err != nil {
+		log.Fatalln("Invalid greeting:", err)
+	}
+
+	fmt.Fprint(conn, flag3+"\n"+flag4+"\n")
+
+	for _, response := range []string{"Input flag: " + flagExpiredMsg,
+		flagYoursMsg} {
+
+		msg, err := reader.ReadString('\n')
+		if err != nil {
+			log.Fatalln("Invalid response:", err)
+		}
+
+		if msg != response {
+			log.Fatalf("Invalid message [%v] instead [%v]",
+				strings.Trim(msg, "\n"),
+				strings.Trim(response, "\n"))
+		}
+	}
+
+	conn.Close()
 
 	// If attempts limit exceeded flag must not be captured
 	newAddr := "127.0.0.1:64000"
 
-	// Start new receiver for test timeouts
-	go FlagReceiver(db.db, priv, newAddr, time.Second, time.Minute, attackFlow)
+	// Start new receiver for test limits
+	go FlagReceiver(db.db, newAddr, time.Minute, NewRateLimiter(1),
+		attackFlow)
 
 	time.Sleep(time.Second) // wait for init listener
 
-	// Just for take timeout
+	// Just for take budget
 	testFlag(newAddr, flag3, flagExpiredMsg)
 
-	// Can't use testFlag, if attempts limit exceeded server does not send
-	// greeting message, and client has not able to send flag
-	conn, err := net.DialTimeout("tcp", newAddr, time.Second)
+	// Budget is per team, not per connection
+	testFlag(newAddr, flag3, attemptsLimitMsg)
+}
+
+func TestLogSubmission(*testing.T) {
+
+	db, err := openDB()
 	if err != nil {
-		log.Fatalln("Connect to receiver failed:", err)
+		log.Fatalln("Open database failed:", err)
 	}
 
-	msg, err := bufio.NewReader(conn).ReadString('\n')
+	defer db.Close()
+
+	// Raw bytes from attacker must not drop submission
+	logSubmission(db.db, 1, "127.0.0.1:4242", "\x00\xff", invalidFlagMsg)
+
+	long := strings.Repeat("б", maxSubmissionFlagLen)
+	logSubmission(db.db, 1, "127.0.0.1:4242", long, invalidFlagMsg)
+
+	subs, err := steward.GetSubmissions(db.db, 1)
 	if err != nil {
-		log.Fatalln("Invalid response:", err)
+		log.Fatalln("Get submissions failed:", err)
 	}
 
-	response := attemptsLimitMsg
+	if len(subs) != 2 || subs[0].Flag != "�" ||
+		subs[0].Addr != "127.0.0.1" || subs[0].Accepted {
+		log.Fatalln("Invalid submissions:", subs)
+	}
 
-	if msg != response {
-		log.Fatalf("Invalid message [%v] instead [%v]",
-			strings.Trim(msg, "\n"),
-			strings.Trim(response, "\n"))
+	if !utf8.ValidString(subs[1].Flag) ||
+		len(subs[1].Flag) > maxSubmissionFlagLen {
+		log.Fatalln("Invalid long flag:", len(subs[1].Flag))
+	}
+}
+
+func TestFlagKeysRotate(*testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		key, err := vexillary.GenerateKey()
+		if err != nil {
+			log.Fatalln("Generate key failed:", err)
+		}
+
+		_, err = steward.AddFlagKey(db.db, key)
+		if err != nil {
+			log.Fatalln("Add key failed:", err)
+		}
+
+		keys, _, err := flagKeys.get(db.db, false)
+		if err != nil || len(keys) != 1 {
+			log.Fatalln("Keys are not cached:", len(keys), err)
+		}
+	}
+
+	time.Sleep(cacheMissRefresh)
+
+	// Rotated key must be found on miss
+	keys, reloaded, err := flagKeys.get(db.db, true)
+	if err != nil || !reloaded || len(keys) != 2 {
+		log.Fatalln("Keys are not reloaded:", len(keys), reloaded, err)
 	}
 }
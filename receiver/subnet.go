@@ -0,0 +1,78 @@
+/**
+ * @file subnet.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief identify team by address
+ *
+ * Tree of team subnets (see steward.SubnetTree) is cached (see cache.go)
+ * and rebuilt if address is not found.
+ */
+
+package receiver
+
+import (
+	"database/sql"
+	"errors"
+	"net"
+)
+
+import (
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+var teams = cache[*steward.SubnetTree]{load: loadSubnetTree}
+
+// loadSubnetTree returns tree of all teams
+func loadSubnetTree(db *sql.DB) (tree *steward.SubnetTree, err error) {
+
+	list, err := steward.GetTeams(db)
+	if err != nil {
+		return
+	}
+
+	return steward.NewSubnetTree(list)
+}
+
+func teamByAddr(db *sql.DB, addr string) (team steward.Team, err error) {
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr // without port
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		err = errors.New("Cannot parse '" + addr + "'")
+		return
+	}
+
+	tree, _, err := teams.get(db, false)
+	if err != nil {
+		return
+	}
+
+	found := tree.Lookup(ip)
+	if found == nil {
+		// Team can be added after last reload
+		var reloaded bool
+
+		tree, reloaded, err = teams.get(db, true)
+		if err != nil {
+			return
+		}
+
+		if reloaded {
+			found = tree.Lookup(ip)
+		}
+	}
+
+	if found == nil {
+		err = errors.New("team not found")
+		return
+	}
+
+	team = *found
+
+	return
+}
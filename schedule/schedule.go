@@ -0,0 +1,119 @@
+/**
+ * @file schedule.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief game schedule
+ *
+ * Game is sequence of named periods, each period is running or paused (e.g.
+ * lunch). Same schedule is used for generate game events, show contest
+ * status and freeze scoreboard.
+ */
+
+package schedule
+
+import (
+	"errors"
+	"time"
+)
+
+// Contest states
+const (
+	NotStarted = "not started"
+	Running    = "running"
+	Paused     = "paused"
+	Completed  = "completed"
+)
+
+// Period of game
+type Period struct {
+	Name    string
+	Start   time.Time
+	End     time.Time
+	Running bool
+}
+
+// Schedule contains periods of game, each period starts at the end of
+// previous
+type Schedule struct {
+	Start   time.Time
+	Periods []Period
+}
+
+// New create empty schedule
+func New(start time.Time) (s Schedule) {
+	s.Start = start
+	return
+}
+
+// Add append period to the end of schedule
+func (s *Schedule) Add(name string, length time.Duration, running bool) {
+	start := s.End()
+	s.Periods = append(s.Periods, Period{Name: name, Start: start,
+		End: start.Add(length), Running: running})
+}
+
+// End returns end time of game
+func (s Schedule) End() time.Time {
+	if len(s.Periods) == 0 {
+		return s.Start
+	}
+	return s.Periods[len(s.Periods)-1].End
+}
+
+// Validate check that game has running periods and lengths are positive
+func (s Schedule) Validate() (err error) {
+
+	running := false
+
+	for _, p := range s.Periods {
+		if !p.End.After(p.Start) {
+			return errors.New("period '" + p.Name + "' is empty")
+		}
+		if p.Running {
+			running = true
+		}
+	}
+
+	if !running {
+		err = errors.New("no running periods")
+	}
+
+	return
+}
+
+// At returns period at time
+func (s Schedule) At(t time.Time) (p Period, ok bool) {
+
+	for _, p = range s.Periods {
+		if !t.Before(p.Start) && t.Before(p.End) {
+			return p, true
+		}
+	}
+
+	return Period{}, false
+}
+
+// State returns contest state at time
+func (s Schedule) State(t time.Time) string {
+
+	if t.Before(s.Start) {
+		return NotStarted
+	}
+
+	p, ok := s.At(t)
+	if !ok {
+		return Completed
+	}
+
+	if p.Running {
+		return Running
+	}
+
+	return Paused
+}
+
+// FreezeTime returns time after which scoreboard is hidden
+func (s Schedule) FreezeTime(darkest time.Duration) time.Time {
+	return s.End().Add(-darkest)
+}
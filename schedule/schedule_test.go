@@ -0,0 +1,75 @@
+/**
+ * @file schedule_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test game schedule
+ */
+
+package schedule_test
+
+import (
+	"log"
+	"testing"
+	"time"
+)
+
+import "github.com/jollheef/tin_foil_hat/schedule"
+
+func TestSchedule(*testing.T) {
+
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	// Three sessions with two breaks
+	sched := schedule.New(start)
+	sched.Add("morning", 2*time.Hour, true)
+	sched.Add("lunch", time.Hour, false)
+	sched.Add("afternoon", 2*time.Hour, true)
+	sched.Add("coffee", 30*time.Minute, false)
+	sched.Add("evening", time.Hour, true)
+
+	err := sched.Validate()
+	if err != nil {
+		log.Fatalln("Valid schedule rejected:", err)
+	}
+
+	if !sched.End().Equal(start.Add(6*time.Hour + 30*time.Minute)) {
+		log.Fatalln("Invalid end of game:", sched.End())
+	}
+
+	for offset, state := range map[time.Duration]string{
+		-time.Minute:                 schedule.NotStarted,
+		0:                            schedule.Running,
+		2 * time.Hour:                schedule.Paused,
+		4*time.Hour + 59*time.Minute: schedule.Running,
+		5*time.Hour + 10*time.Minute: schedule.Paused,
+		5*time.Hour + 30*time.Minute: schedule.Running,
+		6*time.Hour + 30*time.Minute: schedule.Completed,
+	} {
+		if sched.State(start.Add(offset)) != state {
+			log.Fatalln("Invalid state at", offset, "instead", state)
+		}
+	}
+
+	p, ok := sched.At(start.Add(5 * time.Hour))
+	if !ok || p.Name != "coffee" {
+		log.Fatalln("Invalid period:", p)
+	}
+
+	freeze := sched.FreezeTime(time.Hour)
+	if !freeze.Equal(start.Add(5*time.Hour + 30*time.Minute)) {
+		log.Fatalln("Invalid freeze time:", freeze)
+	}
+
+	paused := schedule.New(start)
+	paused.Add("break", time.Hour, false)
+	if paused.Validate() == nil {
+		log.Fatalln("Schedule without running periods accepted")
+	}
+
+	empty := schedule.New(start)
+	empty.Add("first half", 0, true)
+	if empty.Validate() == nil {
+		log.Fatalln("Empty period accepted")
+	}
+}
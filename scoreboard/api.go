@@ -26,6 +26,21 @@ type Attack struct {
 	Timestamp int64
 }
 
+// FlagID describe public flag id for api
+type FlagID struct {
+	Round   int
+	Team    int
+	Service int
+	Vuln    int
+	FlagID  string
+}
+
+// FlagIDs contains flag ids of still valid flags
+type FlagIDs struct {
+	Round   int
+	FlagIDs []FlagID
+}
+
 type broadcast struct {
 	listeners  map[chan<- Attack]bool
 	mutex      sync.Mutex
@@ -118,3 +133,19 @@ func roundHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func flagIDsHandler(w http.ResponseWriter, r *http.Request) {
+	buf, err := json.Marshal(lastFlagIDs)
+	if err != nil {
+		log.Println("Serialization error:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_, err = w.Write(buf)
+	if err != nil {
+		log.Println("Flag ids write error:", err)
+		return
+	}
+}
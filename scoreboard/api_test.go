@@ -11,6 +11,7 @@ package scoreboard
 import (
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -78,3 +79,23 @@ func TestAttackFlowHandler(*testing.T) {
 		}
 	}
 }
+
+func TestFlagIDsHandler(*testing.T) {
+
+	lastFlagIDs = FlagIDs{Round: 7, FlagIDs: []FlagID{
+		{Round: 6, Team: 1, Service: 2, Vuln: 1, FlagID: "user42"}}}
+
+	w := httptest.NewRecorder()
+	flagIDsHandler(w, httptest.NewRequest("GET", "/api/flag_ids", nil))
+
+	var ids FlagIDs
+	err := json.Unmarshal(w.Body.Bytes(), &ids)
+	if err != nil {
+		panic(err)
+	}
+
+	if ids.Round != 7 || len(ids.FlagIDs) != 1 ||
+		ids.FlagIDs[0] != lastFlagIDs.FlagIDs[0] {
+		panic("Invalid flag ids")
+	}
+}
@@ -15,15 +15,42 @@ import (
 	"sort"
 )
 
-import "github.com/jollheef/tin_foil_hat/steward"
+import (
+	"github.com/jollheef/tin_foil_hat/counter"
+	"github.com/jollheef/tin_foil_hat/steward"
+)
 
 var advisoryEnabled = true
 
+var slaEnabled = false
+
 // DisableAdvisory turn off advisory in overall score
 func DisableAdvisory() {
 	advisoryEnabled = false
 }
 
+// EnableSLA multiply totals of services by SLA over the whole game, used
+// by sla scoring formula
+func EnableSLA() {
+	slaEnabled = true
+}
+
+// collectSLA returns { service id : sla } of team up to round
+func collectSLA(db *sql.DB, round, teamID int,
+	services []steward.Service) (sla map[int]float64, err error) {
+
+	sla = make(map[int]float64)
+
+	for _, svc := range services {
+		sla[svc.ID], err = counter.CountSLA(db, round, teamID, svc)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
 func collectTeamResult(db *sql.DB, team steward.Team,
 	services []steward.Service) (tr TeamResult, err error) {
 
@@ -38,6 +65,32 @@ func collectTeamResult(db *sql.DB, team steward.Team,
 
 	tr.Attack = rr.AttackScore
 	tr.Defence = rr.DefenceScore
+	tr.Rating = rr.Rating
+
+	srs, err := steward.GetServiceResults(db, team.ID)
+	if err != nil {
+		// Breakdown is not critical, show zeros
+		srs = nil
+	}
+
+	if slaEnabled && len(srs) != 0 {
+		sla, err := collectSLA(db, rr.Round, team.ID, services)
+		if err != nil {
+			return tr, err
+		}
+
+		tr.Attack, tr.Defence = counter.ApplySLA(srs, sla)
+	}
+
+	for _, svc := range services {
+		var res ServiceResult
+		for _, sr := range srs {
+			if sr.ServiceID == svc.ID {
+				res = ServiceResult{sr.AttackScore, sr.DefenceScore}
+			}
+		}
+		tr.Services = append(tr.Services, res)
+	}
 
 	advisory, err := steward.GetAdvisoryScore(db, team.ID)
 	if err != nil {
@@ -53,7 +106,8 @@ func collectTeamResult(db *sql.DB, team steward.Team,
 	}
 
 	for _, svc := range services {
-		s := steward.Status{round.ID, team.ID, svc.ID, -1}
+		s := steward.Status{Round: round.ID, TeamID: team.ID,
+			ServiceID: svc.ID, State: -1}
 		state, err := steward.GetState(db, s)
 		if err != nil {
 			// Try to get status from previous round
@@ -64,7 +118,14 @@ func collectTeamResult(db *sql.DB, team steward.Team,
 			}
 		}
 
+		// Message is from the same status as state
+		message, err := steward.GetMessage(db, s)
+		if err != nil {
+			message = ""
+		}
+
 		tr.Status = append(tr.Status, state)
+		tr.Messages = append(tr.Messages, message)
 	}
 
 	return
@@ -151,8 +212,20 @@ func CollectLastResult(db *sql.DB) (r Result, err error) {
 		return
 	}
 
+	captures, err := steward.GetVulnCaptures(db)
+	if err != nil {
+		return
+	}
+
 	for _, svc := range services {
 		r.Services = append(r.Services, svc.Name)
+
+		var vulns []int
+		for vuln := 1; vuln <= steward.VulnCount(svc); vuln++ {
+			vulns = append(vulns, captures[svc.ID][vuln])
+		}
+
+		r.Vulns = append(r.Vulns, vulns)
 	}
 
 	for _, team := range teams {
@@ -167,3 +240,25 @@ func CollectLastResult(db *sql.DB) (r Result, err error) {
 
 	return
 }
+
+// CollectFlagIDs returns public flag ids of flags still valid in round,
+// flags are valid for flagLifetime rounds
+func CollectFlagIDs(db *sql.DB, round, flagLifetime int) (ids FlagIDs,
+	err error) {
+
+	ids.Round = round
+	ids.FlagIDs = []FlagID{}
+
+	flgs, err := steward.GetFlagIDs(db, round-flagLifetime+1)
+	if err != nil {
+		return
+	}
+
+	for _, flg := range flgs {
+		ids.FlagIDs = append(ids.FlagIDs, FlagID{Round: flg.Round,
+			Team: flg.TeamID, Service: flg.ServiceID,
+			Vuln: flg.Vuln, FlagID: flg.FlagID})
+	}
+
+	return
+}
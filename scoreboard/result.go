@@ -10,10 +10,20 @@
 
 package scoreboard
 
-import "fmt"
+import (
+	"fmt"
+	"html"
+	"strings"
+)
 
 import "github.com/jollheef/tin_foil_hat/steward"
 
+// ServiceResult contain attack and defence of team in one service
+type ServiceResult struct {
+	Attack  float64
+	Defence float64
+}
+
 // TeamResult contain info for scoreboard
 type TeamResult struct {
 	ID              int
@@ -27,7 +37,10 @@ type TeamResult struct {
 	DefencePercent  float64
 	Advisory        int
 	AdvisoryPercent float64
+	Rating          float64
 	Status          []steward.ServiceState
+	Messages        []string        // public checker messages, as Status
+	Services        []ServiceResult // same order as Result.Services
 }
 
 func td(s string, best bool) string {
@@ -43,7 +56,7 @@ func td(s string, best bool) string {
 func (tr TeamResult) ToHTML(hideScore bool) string {
 
 	var status string
-	for _, s := range tr.Status {
+	for i, s := range tr.Status {
 
 		var label string
 
@@ -59,9 +72,16 @@ func (tr TeamResult) ToHTML(hideScore bool) string {
 			label = "important"
 		}
 
+		// Message of checker is shown to team on hover
+		var title string
+		if i < len(tr.Messages) && tr.Messages[i] != "" {
+			title = fmt.Sprintf(` title="%s"`,
+				html.EscapeString(tr.Messages[i]))
+		}
+
 		status += fmt.Sprintf(
-			`<td width="10%%"><span class="label label-%s">%s</span></td>`,
-			label, s.String())
+			`<td width="10%%"><span class="label label-%s"%s>%s</span></td>`,
+			label, title, s.String())
 	}
 
 	var scoreBest, attackBest, defenceBest, advisoryBest bool
@@ -114,13 +134,28 @@ func (tr ByScore) Less(i, j int) bool { return tr[i].Score > tr[j].Score }
 type Result struct {
 	Teams    []TeamResult
 	Services []string
+	Vulns    [][]int // captures of each vuln, same order as Services
 }
 
 // ToHTML convert Result to HTML
 func (r Result) ToHTML(hideScore bool) string {
 
 	var services string
-	for _, s := range r.Services {
+	for i, s := range r.Services {
+
+		var captures []string
+		if i < len(r.Vulns) && len(r.Vulns[i]) > 1 {
+			for _, count := range r.Vulns[i] {
+				captures = append(captures,
+					fmt.Sprintf("%d", count))
+			}
+		}
+
+		if len(captures) != 0 {
+			s += "<br><small>" + strings.Join(captures, "/") +
+				"</small>"
+		}
+
 		services += "<th>" + s + "</th>"
 	}
 
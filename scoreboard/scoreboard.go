@@ -20,16 +20,13 @@ import (
 	"golang.org/x/net/websocket"
 )
 
-import "github.com/jollheef/tin_foil_hat/steward"
-
-const (
-	contestStateNotAvailable = "state n/a"
-	contestNotStarted        = "not started"
-	contestRunning           = "running"
-	contestPaused            = "paused"
-	contestCompleted         = "completed"
+import (
+	"github.com/jollheef/tin_foil_hat/schedule"
+	"github.com/jollheef/tin_foil_hat/steward"
 )
 
+const contestStateNotAvailable = "state n/a"
+
 var (
 	currentResult string
 	currentRound  string
@@ -68,7 +65,7 @@ func getInfo() string {
 
 	alertType := ""
 
-	if contestStatus == contestRunning {
+	if contestStatus == schedule.Running {
 		alertType = "alert-danger"
 	}
 
@@ -97,8 +94,10 @@ func infoHandler(ws *websocket.Conn) {
 
 var lastResult Result
 
+var lastFlagIDs = FlagIDs{FlagIDs: []FlagID{}}
+
 func resultUpdater(db *sql.DB, updateTimeout time.Duration,
-	darkestTime time.Time) {
+	darkestTime time.Time, flagLifetime int) {
 
 	for {
 		res, err := CollectLastResult(db)
@@ -128,26 +127,21 @@ func resultUpdater(db *sql.DB, updateTimeout time.Duration,
 			round = r.ID
 		}
 
+		ids, err := CollectFlagIDs(db, round, flagLifetime)
+		if err != nil {
+			log.Println("Collect flag ids fail:", err)
+		} else {
+			lastFlagIDs = ids
+		}
+
 		time.Sleep(updateTimeout)
 	}
 }
 
-func stateUpdater(start, lunchStartTime, lunchEndTime, endTime time.Time,
-	timeout time.Duration) {
+func stateUpdater(sched schedule.Schedule, timeout time.Duration) {
 
 	for {
-
-		if time.Now().Before(start) {
-			contestStatus = contestNotStarted
-		} else if time.Now().Before(lunchStartTime) {
-			contestStatus = contestRunning
-		} else if time.Now().Before(lunchEndTime) {
-			contestStatus = contestPaused
-		} else if time.Now().Before(endTime) {
-			contestStatus = contestRunning
-		} else {
-			contestStatus = contestCompleted
-		}
+		contestStatus = sched.State(time.Now())
 
 		time.Sleep(timeout)
 	}
@@ -164,20 +158,17 @@ func handleStaticFileSimple(file, wwwPath string) {
 	handleStaticFile(file, wwwPath+file)
 }
 
-// Scoreboard run scoreboard page
+// Scoreboard run scoreboard page, score is hidden for darkest time before
+// end of game, flag ids are published for flagLifetime rounds
 func Scoreboard(db *sql.DB, attackFlow chan Attack, wwwPath, addr string,
-	updateTimeout time.Duration, start time.Time, half, lunch,
-	darkest time.Duration) (err error) {
+	updateTimeout time.Duration, sched schedule.Schedule,
+	darkest time.Duration, flagLifetime int) (err error) {
 
 	contestStatus = contestStateNotAvailable
-	lunchStart := start.Add(half)
-	lunchEnd := lunchStart.Add(lunch)
-	endTime := lunchEnd.Add(half)
-
-	darkestTime := endTime.Add(-darkest)
 
-	go resultUpdater(db, updateTimeout, darkestTime)
-	go stateUpdater(start, lunchStart, lunchEnd, endTime, updateTimeout)
+	go resultUpdater(db, updateTimeout, sched.FreezeTime(darkest),
+		flagLifetime)
+	go stateUpdater(sched, updateTimeout)
 
 	go advisoryUpdater(db, updateTimeout)
 
@@ -193,6 +184,7 @@ func Scoreboard(db *sql.DB, attackFlow chan Attack, wwwPath, addr string,
 		}))
 
 	http.Handle("/api/result", http.HandlerFunc(resultHandler))
+	http.Handle("/api/flag_ids", http.HandlerFunc(flagIDsHandler))
 
 	files := []string{
 		"/img/glyphicons-halflings-white.png",
@@ -12,6 +12,7 @@ import (
 	"database/sql"
 	"log"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -20,6 +21,7 @@ import (
 )
 
 import (
+	"github.com/jollheef/tin_foil_hat/schedule"
 	"github.com/jollheef/tin_foil_hat/scoreboard"
 	"github.com/jollheef/tin_foil_hat/steward"
 )
@@ -68,6 +70,33 @@ func TestCountScoreboard(*testing.T) {
 	}
 }
 
+func TestVulnCapturesHTML(*testing.T) {
+
+	res := scoreboard.Result{Services: []string{"foo", "bar"},
+		Vulns: [][]int{{3}, {1, 2}}}
+
+	html := res.ToHTML(false)
+
+	if !strings.Contains(html, "<th>foo</th>") ||
+		!strings.Contains(html, "<th>bar<br><small>1/2</small></th>") {
+		log.Fatalln("Invalid vuln captures:", html)
+	}
+}
+
+func TestStatusMessageHTML(*testing.T) {
+
+	tr := scoreboard.TeamResult{Name: "foo",
+		Status:   []steward.ServiceState{steward.StatusMumble},
+		Messages: []string{`Cannot login as "admin" <script>`}}
+
+	html := tr.ToHTML(false)
+
+	if !strings.Contains(html, `title="Cannot login as &#34;admin&#34; `+
+		`&lt;script&gt;"`) {
+		log.Fatalln("Invalid status message:", html)
+	}
+}
+
 func dialWebsocket(db *sql.DB, wg *sync.WaitGroup, i int) {
 
 	origin := "http://localhost/"
@@ -117,9 +146,13 @@ func TestParallelWebSocketConnect(*testing.T) {
 	attackFlow := make(chan scoreboard.Attack, 100)
 
 	go func() {
+		sched := schedule.New(time.Now())
+		sched.Add("first half", time.Minute, true)
+		sched.Add("lunch", time.Minute, false)
+		sched.Add("second half", time.Minute, true)
+
 		err := scoreboard.Scoreboard(db, attackFlow, wwwPath, addr,
-			time.Second, time.Now(), time.Minute, time.Minute,
-			time.Second)
+			time.Second, sched, time.Second, 1)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -8,7 +8,13 @@
 
 package steward
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+)
+
+// ErrAlreadyCaptured returned if flag is already captured by team
+var ErrAlreadyCaptured = errors.New("flag already captured")
 
 func createCapturedFlagTable(db *sql.DB) (err error) {
 
@@ -17,44 +23,61 @@ func createCapturedFlagTable(db *sql.DB) (err error) {
 		id	SERIAL PRIMARY KEY,
 		flag_id	INTEGER NOT NULL,
 		team_id	INTEGER NOT NULL,
-		timestamp	TIMESTAMP with time zone DEFAULT now()
+		round	INTEGER NOT NULL,
+		timestamp	TIMESTAMP with time zone DEFAULT now(),
+		UNIQUE (flag_id, team_id)
 	)`)
 
 	return
 }
 
-// CaptureFlag add correct flag to db
-func CaptureFlag(db *sql.DB, flagID, teamID int) (err error) {
+// CaptureFlag add correct flag to db, round is round of capture (not the
+// round of flag), ErrAlreadyCaptured is returned if flag is already captured
+// by team (e.g. flag sent twice at once)
+func CaptureFlag(db *sql.DB, flagID, teamID, round int) (err error) {
 
 	stmt, err := db.Prepare(
-		"INSERT INTO captured_flag (flag_id, team_id) " +
-			"VALUES ($1, $2)")
+		"INSERT INTO captured_flag (flag_id, team_id, round) " +
+			"VALUES ($1, $2, $3) " +
+			"ON CONFLICT (flag_id, team_id) DO NOTHING")
 	if err != nil {
 		return
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(flagID, teamID)
+	res, err := stmt.Exec(flagID, teamID, round)
+	if err != nil {
+		return
+	}
+
+	added, err := res.RowsAffected()
 	if err != nil {
 		return
 	}
 
+	if added == 0 {
+		err = ErrAlreadyCaptured
+	}
+
 	return
 }
 
-// GetCapturedFlags get all captured flags for team and round
+// GetCapturedFlags get all flags captured by team in round, flags itself can
+// be from previous rounds
 func GetCapturedFlags(db *sql.DB, round, teamID int) (flgs []Flag, err error) {
 
-	stmt, err := db.Prepare("SELECT id, flag, team_id, " +
-		"service_id, cred FROM flag WHERE round=$1")
+	stmt, err := db.Prepare("SELECT flag.id, flag.flag, flag.round, " +
+		"flag.team_id, flag.service_id, flag.cred, flag.vuln FROM flag " +
+		"INNER JOIN captured_flag ON captured_flag.flag_id = flag.id " +
+		"WHERE captured_flag.round=$1 AND captured_flag.team_id=$2")
 	if err != nil {
 		return
 	}
 
 	defer stmt.Close()
 
-	rows, err := stmt.Query(round)
+	rows, err := stmt.Query(round, teamID)
 	if err != nil {
 		return
 	}
@@ -63,53 +86,123 @@ func GetCapturedFlags(db *sql.DB, round, teamID int) (flgs []Flag, err error) {
 
 	for rows.Next() {
 		var flag Flag
-		flag.Round = round
 
-		err = rows.Scan(&flag.ID, &flag.Flag, &flag.TeamID,
-			&flag.ServiceID, &flag.Cred)
+		err = rows.Scan(&flag.ID, &flag.Flag, &flag.Round, &flag.TeamID,
+			&flag.ServiceID, &flag.Cred, &flag.Vuln)
 		if err != nil {
 			return
 		}
 
-		nstmt, err := db.Prepare(
-			"SELECT EXISTS(SELECT id FROM captured_flag " +
-				"WHERE flag_id=$1 AND team_id=$2)")
-		if err != nil {
-			return flgs, err
-		}
+		flgs = append(flgs, flag)
+	}
 
-		defer nstmt.Close()
+	return
+}
 
-		var captured bool
+// AlreadyCaptured returns true if flag already captured by team
+func AlreadyCaptured(db *sql.DB, flagID, teamID int) (captured bool,
+	err error) {
 
-		err = nstmt.QueryRow(flag.ID, teamID).Scan(&captured)
-		if err != nil {
-			return flgs, err
-		}
+	stmt, err := db.Prepare("SELECT EXISTS(SELECT id FROM captured_flag " +
+		"WHERE flag_id=$1 AND team_id=$2)")
+	if err != nil {
+		return
+	}
 
-		if captured {
-			flgs = append(flgs, flag)
-		}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(flagID, teamID).Scan(&captured)
+	if err != nil {
+		return
 	}
 
 	return
 }
 
-// AlreadyCaptured returns false if flag already captured
-func AlreadyCaptured(db *sql.DB, flagID int) (captured bool, err error) {
+// CaptureCount returns count of flag captures in rounds before round
+func CaptureCount(db *sql.DB, flagID, round int) (count int, err error) {
 
-	stmt, err := db.Prepare("SELECT EXISTS(SELECT id FROM captured_flag " +
-		"WHERE flag_id=$1)")
+	stmt, err := db.Prepare("SELECT COUNT(*) FROM captured_flag " +
+		"WHERE flag_id=$1 AND round<$2")
 	if err != nil {
 		return
 	}
 
 	defer stmt.Close()
 
-	err = stmt.QueryRow(flagID).Scan(&captured)
+	err = stmt.QueryRow(flagID, round).Scan(&count)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetVulnCaptures returns count of captures of flags of each vuln,
+// { service id : { vuln : count } }
+func GetVulnCaptures(db *sql.DB) (counts map[int]map[int]int, err error) {
+
+	counts = make(map[int]map[int]int)
+
+	rows, err := db.Query("SELECT flag.service_id, flag.vuln, COUNT(*) " +
+		"FROM captured_flag " +
+		"INNER JOIN flag ON captured_flag.flag_id = flag.id " +
+		"GROUP BY flag.service_id, flag.vuln")
 	if err != nil {
 		return
 	}
 
+	defer rows.Close()
+
+	for rows.Next() {
+		var service, vuln, count int
+
+		err = rows.Scan(&service, &vuln, &count)
+		if err != nil {
+			return
+		}
+
+		if _, ok := counts[service]; !ok {
+			counts[service] = make(map[int]int)
+		}
+
+		counts[service][vuln] = count
+	}
+
+	return
+}
+
+// Capture describe captured flag, who captured it from whom
+type Capture struct {
+	TeamID    int // attacker
+	VictimID  int
+	ServiceID int
+	Round     int // round of capture
+}
+
+// GetCaptures returns all captured flags
+func GetCaptures(db *sql.DB) (caps []Capture, err error) {
+
+	rows, err := db.Query("SELECT captured_flag.team_id, flag.team_id, " +
+		"flag.service_id, captured_flag.round FROM captured_flag " +
+		"INNER JOIN flag ON captured_flag.flag_id = flag.id " +
+		"ORDER BY captured_flag.id")
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Capture
+
+		err = rows.Scan(&c.TeamID, &c.VictimID, &c.ServiceID, &c.Round)
+		if err != nil {
+			return
+		}
+
+		caps = append(caps, c)
+	}
+
 	return
 }
@@ -21,7 +21,7 @@ func TestCaptureFlag(t *testing.T) {
 
 	defer db.Close()
 
-	err = steward.CaptureFlag(db.db, 10, 20)
+	err = steward.CaptureFlag(db.db, 10, 20, 1)
 	if err != nil {
 		log.Fatalln("Capture flag failed:", err)
 	}
@@ -37,9 +37,9 @@ func TestGetCapturedFlags(t *testing.T) {
 	team_id := 1
 
 	flg1 := steward.Flag{ID: 1, Flag: "f", Round: round, TeamID: team_id,
-		ServiceID: 1, Cred: "1:2"}
+		ServiceID: 1, Cred: "1:2", Vuln: 1}
 	flg2 := steward.Flag{ID: 2, Flag: "b", Round: round, TeamID: team_id,
-		ServiceID: 1, Cred: "1:2"}
+		ServiceID: 1, Cred: "1:2", Vuln: 2}
 
 	err = steward.AddFlag(db.db, flg1)
 	if err != nil {
@@ -51,8 +51,8 @@ func TestGetCapturedFlags(t *testing.T) {
 		log.Fatalln("Add flag failed:", err)
 	}
 
-	err = steward.CaptureFlag(db.db, flg1.ID, 20)
-	err = steward.CaptureFlag(db.db, flg2.ID, 30)
+	err = steward.CaptureFlag(db.db, flg1.ID, 20, round)
+	err = steward.CaptureFlag(db.db, flg2.ID, 30, round)
 
 	flags1, err := steward.GetCapturedFlags(db.db, round, 20)
 	if err != nil {
@@ -77,6 +77,45 @@ func TestGetCapturedFlags(t *testing.T) {
 	}
 }
 
+func TestGetCapturedFlagsFromPastRound(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	flg := steward.Flag{ID: 1, Flag: "f", Round: 1, TeamID: 1,
+		ServiceID: 1, Cred: "1:2"}
+
+	err = steward.AddFlag(db.db, flg)
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	// Flag from first round captured in second round
+	err = steward.CaptureFlag(db.db, flg.ID, 20, 2)
+	if err != nil {
+		log.Fatalln("Capture flag failed:", err)
+	}
+
+	flags, err := steward.GetCapturedFlags(db.db, 1, 20)
+	if err != nil {
+		log.Fatalln("Get captured flags failed:", err)
+	}
+
+	if len(flags) != 0 {
+		log.Fatalln("Flag attributed to round of flag")
+	}
+
+	flags, err = steward.GetCapturedFlags(db.db, 2, 20)
+	if err != nil {
+		log.Fatalln("Get captured flags failed:", err)
+	}
+
+	if len(flags) != 1 || flags[0] != flg {
+		log.Fatalln("Flag is not attributed to round of capture")
+	}
+}
+
 func TestAlreadyCaptured(t *testing.T) {
 
 	db, err := openDB()
@@ -88,9 +127,9 @@ func TestAlreadyCaptured(t *testing.T) {
 	flg2 := steward.Flag{ID: 2, Flag: "b", Round: 1, TeamID: 1,
 		ServiceID: 1, Cred: "1:2"}
 
-	err = steward.CaptureFlag(db.db, flg1.ID, 20)
+	err = steward.CaptureFlag(db.db, flg1.ID, 20, 1)
 
-	captured, err := steward.AlreadyCaptured(db.db, flg1.ID)
+	captured, err := steward.AlreadyCaptured(db.db, flg1.ID, 20)
 	if err != nil {
 		log.Fatalln("Already captured check failed:", err)
 	}
@@ -99,7 +138,22 @@ func TestAlreadyCaptured(t *testing.T) {
 		log.Fatalln("Captured flag is not captured")
 	}
 
-	captured, err = steward.AlreadyCaptured(db.db, flg2.ID)
+	err = steward.CaptureFlag(db.db, flg1.ID, 20, 2)
+	if err != steward.ErrAlreadyCaptured {
+		log.Fatalln("Flag captured twice:", err)
+	}
+
+	// Other team still can capture flag
+	captured, err = steward.AlreadyCaptured(db.db, flg1.ID, 30)
+	if err != nil {
+		log.Fatalln("Already captured check failed:", err)
+	}
+
+	if captured {
+		log.Fatalln("Flag captured by other team is captured")
+	}
+
+	captured, err = steward.AlreadyCaptured(db.db, flg2.ID, 20)
 	if err != nil {
 		log.Fatalln("Already captured check failed:", err)
 	}
@@ -108,3 +162,87 @@ func TestAlreadyCaptured(t *testing.T) {
 		log.Fatalln("Not captured flag is captured")
 	}
 }
+
+func TestCaptureCount(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	err = steward.CaptureFlag(db.db, 1, 20, 3)
+	err = steward.CaptureFlag(db.db, 1, 30, 2)
+	err = steward.CaptureFlag(db.db, 1, 40, 4)
+	err = steward.CaptureFlag(db.db, 2, 40, 1)
+	if err != nil {
+		log.Fatalln("Capture flag failed:", err)
+	}
+
+	for round, mustBe := range []int{0, 0, 0, 1, 2, 3} {
+
+		count, err := steward.CaptureCount(db.db, 1, round)
+		if err != nil {
+			log.Fatalln("Get capture count failed:", err)
+		}
+
+		if count != mustBe {
+			log.Fatalln("Capture count before round", round, "is",
+				count, "instead", mustBe)
+		}
+	}
+}
+
+func TestGetVulnCaptures(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	flg1 := steward.Flag{ID: 1, Flag: "f", Round: 1, TeamID: 1,
+		ServiceID: 1, Cred: "1:2", Vuln: 1}
+	flg2 := steward.Flag{ID: 2, Flag: "b", Round: 1, TeamID: 1,
+		ServiceID: 1, Cred: "1:2", Vuln: 2}
+
+	steward.AddFlag(db.db, flg1)
+	steward.AddFlag(db.db, flg2)
+
+	steward.CaptureFlag(db.db, flg1.ID, 20, 1)
+	steward.CaptureFlag(db.db, flg2.ID, 20, 1)
+	steward.CaptureFlag(db.db, flg2.ID, 30, 1)
+
+	counts, err := steward.GetVulnCaptures(db.db)
+	if err != nil {
+		log.Fatalln("Get vuln captures failed:", err)
+	}
+
+	if counts[1][1] != 1 || counts[1][2] != 2 {
+		log.Fatalln("Invalid vuln captures:", counts)
+	}
+}
+
+func TestGetCaptures(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	err = steward.AddFlag(db.db, steward.Flag{ID: -1, Flag: "f", Round: 1,
+		TeamID: 1, ServiceID: 2, Cred: "1:2"})
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	err = steward.CaptureFlag(db.db, 1, 3, 2)
+	if err != nil {
+		log.Fatalln("Capture flag failed:", err)
+	}
+
+	caps, err := steward.GetCaptures(db.db)
+	if err != nil {
+		log.Fatalln("Get captures failed:", err)
+	}
+
+	if len(caps) != 1 || caps[0] != (steward.Capture{TeamID: 3,
+		VictimID: 1, ServiceID: 2, Round: 2}) {
+		log.Fatalln("Invalid captures:", caps)
+	}
+}
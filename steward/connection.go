@@ -0,0 +1,80 @@
+/**
+ * @file connection.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief queries for connection table
+ *
+ * Connections of teams to services of other teams, imported from traffic
+ * records of game network (e.g. conntrack or netflow of router).
+ */
+
+package steward
+
+import "database/sql"
+
+// Connection contains count of connections of team to service of victim
+type Connection struct {
+	TeamID    int
+	VictimID  int
+	ServiceID int
+	Count     int
+}
+
+func createConnectionTable(db *sql.DB) (err error) {
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS "connection" (
+		id	SERIAL PRIMARY KEY,
+		team_id	INTEGER NOT NULL,
+		victim_id	INTEGER NOT NULL,
+		service_id	INTEGER NOT NULL,
+		count	INTEGER NOT NULL
+	)`)
+
+	return
+}
+
+// AddConnection add imported connections to database
+func AddConnection(db *sql.DB, c Connection) (err error) {
+
+	stmt, err := db.Prepare("INSERT INTO connection (team_id, victim_id, " +
+		"service_id, count) VALUES ($1, $2, $3, $4)")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.Exec(c.TeamID, c.VictimID, c.ServiceID, c.Count)
+
+	return
+}
+
+// GetConnections returns count of connections of team to service of victim,
+// summed over all imports
+func GetConnections(db *sql.DB) (conns []Connection, err error) {
+
+	rows, err := db.Query("SELECT team_id, victim_id, service_id, " +
+		"SUM(count) FROM connection " +
+		"GROUP BY team_id, victim_id, service_id " +
+		"ORDER BY team_id, victim_id, service_id")
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Connection
+
+		err = rows.Scan(&c.TeamID, &c.VictimID, &c.ServiceID, &c.Count)
+		if err != nil {
+			return
+		}
+
+		conns = append(conns, c)
+	}
+
+	return
+}
@@ -0,0 +1,44 @@
+/**
+ * @file connection_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test work with connection table
+ */
+
+package steward_test
+
+import (
+	"log"
+	"testing"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestConnections(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	for _, c := range []steward.Connection{
+		{TeamID: 1, VictimID: 2, ServiceID: 1, Count: 3},
+		{TeamID: 1, VictimID: 2, ServiceID: 1, Count: 4}, // next import
+		{TeamID: 2, VictimID: 1, ServiceID: 1, Count: 1},
+	} {
+		err = steward.AddConnection(db.db, c)
+		if err != nil {
+			log.Fatalln("Add connection failed:", err)
+		}
+	}
+
+	conns, err := steward.GetConnections(db.db)
+	if err != nil {
+		log.Fatalln("Get connections failed:", err)
+	}
+
+	if len(conns) != 2 || conns[0] != (steward.Connection{TeamID: 1,
+		VictimID: 2, ServiceID: 1, Count: 7}) {
+		log.Fatalln("Invalid connections:", conns)
+	}
+}
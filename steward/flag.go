@@ -8,7 +8,10 @@
 
 package steward
 
-import "database/sql"
+import (
+	"database/sql"
+	"fmt"
+)
 
 // Flag contains info about flag
 type Flag struct {
@@ -18,6 +21,8 @@ type Flag struct {
 	TeamID    int
 	ServiceID int
 	Cred      string
+	Vuln      int    // flag store of service, from 1
+	FlagID    string // public hint for attackers, e.g. user with flag
 }
 
 func createFlagTable(db *sql.DB) (err error) {
@@ -29,30 +34,52 @@ func createFlagTable(db *sql.DB) (err error) {
 		flag	TEXT NOT NULL UNIQUE,
 		team_id	INTEGER NOT NULL,
 		service_id	INTEGER NOT NULL,
-		cred	TEXT NOT NULL
+		cred	TEXT NOT NULL,
+		vuln	INTEGER NOT NULL DEFAULT 1,
+		flag_id	TEXT NOT NULL DEFAULT ''
 	)`)
+	if err != nil {
+		return
+	}
 
+	// Cred of flag store is got by round, team, service and vuln
+	_, err = db.Exec(`
+	CREATE UNIQUE INDEX IF NOT EXISTS flag_store_unique ON flag
+		(round, team_id, service_id, vuln)`)
 	return
 }
 
-// AddFlag add flag to database
+// AddFlag add flag to database, only one flag can be added to each flag
+// store (vuln) of service in round
 func AddFlag(db *sql.DB, flg Flag) error {
 
 	stmt, err := db.Prepare("INSERT INTO flag " +
-		"(round, team_id, service_id, flag, cred) " +
-		"VALUES ($1, $2, $3, $4, $5)")
+		"(round, team_id, service_id, flag, cred, vuln, flag_id) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7) " +
+		"ON CONFLICT (round, team_id, service_id, vuln) DO NOTHING")
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(flg.Round, flg.TeamID, flg.ServiceID,
-		flg.Flag, flg.Cred)
+	res, err := stmt.Exec(flg.Round, flg.TeamID, flg.ServiceID,
+		flg.Flag, flg.Cred, flg.Vuln, flg.FlagID)
+	if err != nil {
+		return err
+	}
+
+	added, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if added == 0 {
+		return fmt.Errorf("flag of round %d, team %d, service %d, "+
+			"vuln %d already exist", flg.Round, flg.TeamID,
+			flg.ServiceID, flg.Vuln)
+	}
+
 	return nil
 }
 
@@ -81,8 +108,8 @@ func GetFlagInfo(db *sql.DB, flag string) (flg Flag, err error) {
 	flg.Flag = flag
 
 	stmt, err := db.Prepare(
-		"SELECT id, round, team_id, service_id, cred " +
-			"FROM flag WHERE flag=$1")
+		"SELECT id, round, team_id, service_id, cred, vuln, " +
+			"flag_id FROM flag WHERE flag=$1")
 	if err != nil {
 		return
 	}
@@ -90,7 +117,7 @@ func GetFlagInfo(db *sql.DB, flag string) (flg Flag, err error) {
 	defer stmt.Close()
 
 	err = stmt.QueryRow(flag).Scan(&flg.ID, &flg.Round, &flg.TeamID,
-		&flg.ServiceID, &flg.Cred)
+		&flg.ServiceID, &flg.Cred, &flg.Vuln, &flg.FlagID)
 	if err != nil {
 		return
 	}
@@ -98,21 +125,96 @@ func GetFlagInfo(db *sql.DB, flag string) (flg Flag, err error) {
 	return
 }
 
-// GetCred returns credentials for check flag in service
-func GetCred(db *sql.DB, round, team, service int) (flag, cred string, err error) {
+// GetFlagIDs returns public flag ids of flags from round and later, flag
+// and cred are not set
+func GetFlagIDs(db *sql.DB, fromRound int) (flgs []Flag, err error) {
+
+	stmt, err := db.Prepare("SELECT round, team_id, service_id, vuln, " +
+		"flag_id FROM flag WHERE round>=$1 AND flag_id!='' ORDER BY id")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query(fromRound)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var flg Flag
+
+		err = rows.Scan(&flg.Round, &flg.TeamID, &flg.ServiceID,
+			&flg.Vuln, &flg.FlagID)
+		if err != nil {
+			return
+		}
+
+		flgs = append(flgs, flg)
+	}
+
+	return
+}
+
+// GetCred returns credentials for check flag in vuln of service
+func GetCred(db *sql.DB, round, team, service, vuln int) (flag, cred string,
+	err error) {
 
 	stmt, err := db.Prepare("SELECT flag, cred FROM flag WHERE round=$1" +
-		" AND team_id=$2 AND service_id=$3")
+		" AND team_id=$2 AND service_id=$3 AND vuln=$4")
 	if err != nil {
 		return
 	}
 
 	defer stmt.Close()
 
-	err = stmt.QueryRow(round, team, service).Scan(&flag, &cred)
+	err = stmt.QueryRow(round, team, service, vuln).Scan(&flag, &cred)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetCheckedFlags returns flags of service for team from rounds in range,
+// which was successfully checked in their round (last status of round is up)
+func GetCheckedFlags(db *sql.DB, fromRound, toRound, team, service int) (
+	flgs []Flag, err error) {
+
+	stmt, err := db.Prepare("SELECT f.id, f.flag, f.round, f.team_id, " +
+		"f.service_id, f.cred, f.vuln FROM flag f " +
+		"WHERE f.round>=$1 AND f.round<=$2 " +
+		"AND f.team_id=$3 AND f.service_id=$4 " +
+		"AND (SELECT s.state FROM status s WHERE s.round=f.round " +
+		"AND s.team_id=f.team_id AND s.service_id=f.service_id " +
+		"ORDER BY s.id DESC LIMIT 1)=$5 ORDER BY f.id")
 	if err != nil {
 		return
 	}
 
+	defer stmt.Close()
+
+	rows, err := stmt.Query(fromRound, toRound, team, service, StatusUP)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var flg Flag
+
+		err = rows.Scan(&flg.ID, &flg.Flag, &flg.Round, &flg.TeamID,
+			&flg.ServiceID, &flg.Cred, &flg.Vuln)
+		if err != nil {
+			return
+		}
+
+		flgs = append(flgs, flg)
+	}
+
 	return
 }
@@ -0,0 +1,110 @@
+/**
+ * @file flag_key.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief queries for flag key table
+ *
+ * Keys for sign flags are kept in database, so flags stay valid after
+ * restart. Flags are signed by last key, any key of game is valid.
+ */
+
+package steward
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+// FlagKey contains key for sign flags
+type FlagKey struct {
+	ID        int
+	Key       []byte
+	Timestamp time.Time
+}
+
+func createFlagKeyTable(db *sql.DB) (err error) {
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS "flag_key" (
+		id	SERIAL PRIMARY KEY,
+		key	TEXT NOT NULL,
+		timestamp	TIMESTAMP with time zone DEFAULT now()
+	)`)
+
+	return
+}
+
+// AddFlagKey add key to database, added key is used for sign new flags
+func AddFlagKey(db *sql.DB, key []byte) (id int, err error) {
+
+	stmt, err := db.Prepare("INSERT INTO flag_key (key) VALUES ($1) " +
+		"RETURNING id")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRow(hex.EncodeToString(key)).Scan(&id)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetFlagKeys returns all keys, from last to first
+func GetFlagKeys(db *sql.DB) (keys []FlagKey, err error) {
+
+	rows, err := db.Query("SELECT id, key, timestamp FROM flag_key " +
+		"ORDER BY id DESC")
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var key FlagKey
+		var rawKey string
+
+		err = rows.Scan(&key.ID, &rawKey, &key.Timestamp)
+		if err != nil {
+			return
+		}
+
+		key.Key, err = hex.DecodeString(rawKey)
+		if err != nil {
+			return
+		}
+
+		keys = append(keys, key)
+	}
+
+	return
+}
+
+// GetLastFlagKey returns key for sign new flags
+func GetLastFlagKey(db *sql.DB) (key FlagKey, err error) {
+
+	stmt, err := db.Prepare("SELECT id, key, timestamp FROM flag_key " +
+		"WHERE id = (SELECT MAX(id) FROM flag_key)")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	var rawKey string
+
+	err = stmt.QueryRow().Scan(&key.ID, &rawKey, &key.Timestamp)
+	if err != nil {
+		return
+	}
+
+	key.Key, err = hex.DecodeString(rawKey)
+
+	return
+}
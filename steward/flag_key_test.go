@@ -0,0 +1,57 @@
+/**
+ * @file flag_key_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test work with flag key table
+ */
+
+package steward_test
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestFlagKeys(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	_, err = steward.GetLastFlagKey(db.db)
+	if err == nil {
+		log.Fatalln("Key in empty database already exist")
+	}
+
+	first := []byte{1, 2, 3}
+	last := []byte{4, 5, 6}
+
+	for _, key := range [][]byte{first, last} {
+		_, err = steward.AddFlagKey(db.db, key)
+		if err != nil {
+			log.Fatalln("Add key failed:", err)
+		}
+	}
+
+	key, err := steward.GetLastFlagKey(db.db)
+	if err != nil {
+		log.Fatalln("Get last key failed:", err)
+	}
+
+	if key.ID != 2 || !bytes.Equal(key.Key, last) {
+		log.Fatalln("Invalid last key:", key)
+	}
+
+	keys, err := steward.GetFlagKeys(db.db)
+	if err != nil {
+		log.Fatalln("Get keys failed:", err)
+	}
+
+	if len(keys) != 2 || !bytes.Equal(keys[1].Key, first) {
+		log.Fatalln("Invalid keys:", keys)
+	}
+}
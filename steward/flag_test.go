@@ -9,6 +9,7 @@
 package steward_test
 
 import (
+	"fmt"
 	"log"
 	"testing"
 )
@@ -26,6 +27,13 @@ func TestAddFlag(t *testing.T) {
 	if err != nil {
 		log.Fatalln("Add flag failed:", err)
 	}
+
+	// Flag store of round already has flag
+	err = steward.AddFlag(db.db, steward.Flag{ID: 2, Flag: "kolka",
+		Round: 1, TeamID: 2, ServiceID: 3, Cred: "3:4"})
+	if err == nil {
+		log.Fatalln("Second flag of flag store added")
+	}
 }
 
 func TestFlagExist(t *testing.T) {
@@ -57,7 +65,7 @@ func TestGetFlagInfo(t *testing.T) {
 	defer db.Close()
 
 	flg := steward.Flag{ID: 1, Flag: "asdfasdf", Round: 5345, TeamID: 433,
-		ServiceID: 353, Cred: "1:2"}
+		ServiceID: 353, Cred: "1:2", Vuln: 2, FlagID: "user42"}
 
 	err = steward.AddFlag(db.db, flg)
 
@@ -77,18 +85,99 @@ func TestGetCred(t *testing.T) {
 
 	defer db.Close()
 
-	flg := steward.Flag{ID: 1, Flag: "asdfasdf", Round: 5345, TeamID: 433,
-		ServiceID: 353, Cred: "1:2"}
+	flg1 := steward.Flag{ID: 1, Flag: "asdfasdf", Round: 5345, TeamID: 433,
+		ServiceID: 353, Cred: "1:2", Vuln: 1}
+	flg2 := steward.Flag{ID: 2, Flag: "qwerqwer", Round: 5345, TeamID: 433,
+		ServiceID: 353, Cred: "3:4", Vuln: 2}
 
-	err = steward.AddFlag(db.db, flg)
+	err = steward.AddFlag(db.db, flg1)
+	err = steward.AddFlag(db.db, flg2)
+	if err != nil {
+		log.Fatalln("Add flag failed:", err)
+	}
+
+	for _, flg := range []steward.Flag{flg1, flg2} {
+
+		flag, cred, err := steward.GetCred(db.db, flg.Round,
+			flg.TeamID, flg.ServiceID, flg.Vuln)
+		if err != nil {
+			log.Fatalln("Get cred failed:", err)
+		}
+
+		if flag != flg.Flag || cred != flg.Cred {
+			log.Fatalln("Gotten cred invalid")
+		}
+	}
+}
+
+func TestGetCheckedFlags(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	team := 12
+	service := 7
+
+	states := []steward.ServiceState{steward.StatusUP,
+		steward.StatusCorrupt, steward.StatusUP, steward.StatusUP}
+
+	for i, state := range states {
+		round := i + 1
+
+		err = steward.AddFlag(db.db, steward.Flag{ID: -1,
+			Flag: fmt.Sprintf("flag%d", round), Round: round,
+			TeamID: team, ServiceID: service, Vuln: 1})
+		if err != nil {
+			log.Fatalln("Add flag failed:", err)
+		}
+
+		// Put status, then check status
+		steward.PutStatus(db.db, steward.Status{Round: round,
+			TeamID: team, ServiceID: service,
+			State: steward.StatusUP})
+		steward.PutStatus(db.db, steward.Status{Round: round,
+			TeamID: team, ServiceID: service, State: state})
+	}
+
+	flgs, err := steward.GetCheckedFlags(db.db, 2, 3, team, service)
+	if err != nil {
+		log.Fatalln("Get checked flags failed:", err)
+	}
+
+	if len(flgs) != 1 || flgs[0].Flag != "flag3" {
+		log.Fatalln("Invalid checked flags:", flgs)
+	}
+}
+
+func TestGetFlagIDs(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	for round := 1; round <= 3; round++ {
+		flagID := fmt.Sprintf("user%d", round)
+		if round == 3 {
+			flagID = "" // checker does not return flag id
+		}
+
+		err = steward.AddFlag(db.db, steward.Flag{ID: -1,
+			Flag: fmt.Sprintf("flag%d", round), Round: round,
+			TeamID: 1, ServiceID: 2, Cred: "secret", Vuln: 1,
+			FlagID: flagID})
+		if err != nil {
+			log.Fatalln("Add flag failed:", err)
+		}
+	}
 
-	flag, cred, err := steward.GetCred(db.db, flg.Round, flg.TeamID,
-		flg.ServiceID)
+	flgs, err := steward.GetFlagIDs(db.db, 2)
 	if err != nil {
-		log.Fatalln("Get cred failed:", err)
+		log.Fatalln("Get flag ids failed:", err)
 	}
 
-	if flag != flg.Flag || cred != flg.Cred {
-		log.Fatalln("Gotten cred invalid")
+	if len(flgs) != 1 || flgs[0] != (steward.Flag{Round: 2, TeamID: 1,
+		ServiceID: 2, Vuln: 1, FlagID: "user2"}) {
+		log.Fatalln("Invalid flag ids:", flgs)
 	}
 }
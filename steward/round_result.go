@@ -13,13 +13,15 @@ import (
 	"sync"
 )
 
-// RoundResult contains info about result of round
+// RoundResult contains info about result of round, attack and defence
+// scores are cumulative, rating is current team rating after round
 type RoundResult struct {
 	ID           int
 	TeamID       int
 	Round        int
 	AttackScore  float64
 	DefenceScore float64
+	Rating       float64
 }
 
 func createRoundResultTable(db *sql.DB) (err error) {
@@ -31,6 +33,7 @@ func createRoundResultTable(db *sql.DB) (err error) {
 		round	INTEGER,
 		attack_score	FLOAT(24),
 		defence_score	FLOAT(24),
+		rating	FLOAT(24) DEFAULT 0,
 		UNIQUE (team_id, round)
 	);`)
 
@@ -56,8 +59,8 @@ func AddRoundResult(db *sql.DB, res RoundResult) (id int, err error) {
 	}
 
 	stmt, err := db.Prepare("INSERT INTO round_result " +
-		"(team_id, round, attack_score, defence_score) " +
-		"VALUES ($1, $2, $3, $4) RETURNING id")
+		"(team_id, round, attack_score, defence_score, rating) " +
+		"VALUES ($1, $2, $3, $4, $5) RETURNING id")
 	if err != nil {
 		return
 	}
@@ -65,7 +68,7 @@ func AddRoundResult(db *sql.DB, res RoundResult) (id int, err error) {
 	defer stmt.Close()
 
 	err = stmt.QueryRow(res.TeamID, res.Round, res.AttackScore,
-		res.DefenceScore).Scan(&id)
+		res.DefenceScore, res.Rating).Scan(&id)
 	if err != nil {
 		return
 	}
@@ -76,8 +79,8 @@ func AddRoundResult(db *sql.DB, res RoundResult) (id int, err error) {
 // GetRoundResult get result for team and round
 func GetRoundResult(db *sql.DB, teamID, round int) (res RoundResult, err error) {
 
-	stmt, err := db.Prepare("SELECT id, attack_score, defence_score " +
-		"FROM round_result WHERE team_id=$1 AND round=$2")
+	stmt, err := db.Prepare("SELECT id, attack_score, defence_score, " +
+		"rating FROM round_result WHERE team_id=$1 AND round=$2")
 	if err != nil {
 		return
 	}
@@ -85,7 +88,7 @@ func GetRoundResult(db *sql.DB, teamID, round int) (res RoundResult, err error)
 	defer stmt.Close()
 
 	err = stmt.QueryRow(teamID, round).Scan(&res.ID, &res.AttackScore,
-		&res.DefenceScore)
+		&res.DefenceScore, &res.Rating)
 	if err != nil {
 		return
 	}
@@ -99,8 +102,8 @@ func GetRoundResult(db *sql.DB, teamID, round int) (res RoundResult, err error)
 // GetLastResult get last round result for team
 func GetLastResult(db *sql.DB, teamID int) (res RoundResult, err error) {
 
-	stmt, err := db.Prepare("SELECT id, round, attack_score, defence_score " +
-		"FROM round_result WHERE team_id=$1 " +
+	stmt, err := db.Prepare("SELECT id, round, attack_score, " +
+		"defence_score, rating FROM round_result WHERE team_id=$1 " +
 		"AND round = (SELECT MAX(round) FROM round_result " +
 		"WHERE team_id=$1)")
 	if err != nil {
@@ -110,7 +113,7 @@ func GetLastResult(db *sql.DB, teamID int) (res RoundResult, err error) {
 	defer stmt.Close()
 
 	err = stmt.QueryRow(teamID).Scan(&res.ID, &res.Round, &res.AttackScore,
-		&res.DefenceScore)
+		&res.DefenceScore, &res.Rating)
 	if err != nil {
 		return
 	}
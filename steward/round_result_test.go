@@ -52,7 +52,8 @@ func TestGetRoundResult(t *testing.T) {
 	first := steward.RoundResult{ID: -1, TeamID: 10, Round: 1,
 		AttackScore: 30, DefenceScore: 40}
 	second := steward.RoundResult{ID: -1, TeamID: first.TeamID,
-		Round: first.Round + 1, AttackScore: 130, DefenceScore: 140}
+		Round: first.Round + 1, AttackScore: 130, DefenceScore: 140,
+		Rating: 1016}
 
 	_, err = steward.AddRoundResult(db.db, first)
 	if err != nil {
@@ -90,4 +91,9 @@ func TestGetRoundResult(t *testing.T) {
 		log.Fatalln("Invalid defence score value", res.DefenceScore,
 			defence_sum)
 	}
+
+	// Rating is not cumulative
+	if res.Rating != second.Rating {
+		log.Fatalln("Invalid rating value", res.Rating, second.Rating)
+	}
 }
@@ -17,6 +17,20 @@ type Service struct {
 	Port        int
 	CheckerPath string
 	UDP         bool
+	Weight      float64 // relative cost of service, zero means one
+	// Checker prints json result instead of exit code protocol
+	JSONProtocol bool
+	// Name of built-in checker, used instead of CheckerPath if set
+	Checker string
+	// Count of persistent checker processes, zero means fork per call
+	Workers int
+	// Count of independent flag stores, zero means one
+	Vulns int
+	// Liveness probe run before checker (tcp, banner, udp, tls, http or
+	// none), empty means tcp for tcp services and none for udp services
+	Probe       string
+	ProbeSend   string // data (path for http) sent by probe
+	ProbeExpect string // regexp of response (status code for http)
 }
 
 func createServiceTable(db *sql.DB) (err error) {
@@ -27,7 +41,15 @@ func createServiceTable(db *sql.DB) (err error) {
 		name	TEXT NOT NULL,
 		port	INTEGER NOT NULL,
 		checker_path	TEXT NOT NULL,
-		udp	BOOLEAN NOT NULL
+		udp	BOOLEAN NOT NULL,
+		weight	FLOAT(24) NOT NULL DEFAULT 1,
+		json_protocol	BOOLEAN NOT NULL DEFAULT FALSE,
+		checker	TEXT NOT NULL DEFAULT '',
+		workers	INTEGER NOT NULL DEFAULT 0,
+		vulns	INTEGER NOT NULL DEFAULT 1,
+		probe	TEXT NOT NULL DEFAULT '',
+		probe_send	TEXT NOT NULL DEFAULT '',
+		probe_expect	TEXT NOT NULL DEFAULT ''
 	)`)
 
 	return
@@ -37,15 +59,20 @@ func createServiceTable(db *sql.DB) (err error) {
 func AddService(db *sql.DB, svc Service) error {
 
 	stmt, err := db.Prepare(
-		"INSERT INTO service (name, port, checker_path, udp) " +
-			"VALUES ($1, $2, $3, $4)")
+		"INSERT INTO service (name, port, checker_path, udp, weight, " +
+			"json_protocol, checker, workers, vulns, probe, " +
+			"probe_send, probe_expect) " +
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, " +
+			"$12)")
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(svc.Name, svc.Port, svc.CheckerPath, svc.UDP)
+	_, err = stmt.Exec(svc.Name, svc.Port, svc.CheckerPath, svc.UDP,
+		svc.Weight, svc.JSONProtocol, svc.Checker, svc.Workers,
+		svc.Vulns, svc.Probe, svc.ProbeSend, svc.ProbeExpect)
 
 	if err != nil {
 		return err
@@ -57,8 +84,9 @@ func AddService(db *sql.DB, svc Service) error {
 // GetServices get all services from database
 func GetServices(db *sql.DB) (services []Service, err error) {
 
-	rows, err := db.Query("SELECT id,name, port, checker_path, udp " +
-		"FROM service ")
+	rows, err := db.Query("SELECT id,name, port, checker_path, udp, " +
+		"weight, json_protocol, checker, workers, vulns, probe, " +
+		"probe_send, probe_expect FROM service ")
 	if err != nil {
 		return
 	}
@@ -69,7 +97,9 @@ func GetServices(db *sql.DB) (services []Service, err error) {
 		var svc Service
 
 		err = rows.Scan(&svc.ID, &svc.Name, &svc.Port, &svc.CheckerPath,
-			&svc.UDP)
+			&svc.UDP, &svc.Weight, &svc.JSONProtocol, &svc.Checker,
+			&svc.Workers, &svc.Vulns, &svc.Probe, &svc.ProbeSend,
+			&svc.ProbeExpect)
 		if err != nil {
 			return
 		}
@@ -79,3 +109,11 @@ func GetServices(db *sql.DB) (services []Service, err error) {
 
 	return
 }
+
+// VulnCount returns count of flag stores of service
+func VulnCount(svc Service) int {
+	if svc.Vulns < 1 {
+		return 1
+	}
+	return svc.Vulns
+}
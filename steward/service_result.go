@@ -0,0 +1,95 @@
+/**
+ * @file service_result.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief queries for service result table
+ */
+
+package steward
+
+import "database/sql"
+
+// ServiceResult contains attack and defence of team in one service
+type ServiceResult struct {
+	ID           int
+	TeamID       int
+	ServiceID    int
+	Round        int
+	AttackScore  float64
+	DefenceScore float64
+}
+
+func createServiceResultTable(db *sql.DB) (err error) {
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS "service_result" (
+		id	SERIAL PRIMARY KEY,
+		team_id	INTEGER NOT NULL,
+		service_id	INTEGER NOT NULL,
+		round	INTEGER NOT NULL,
+		attack_score	FLOAT(24),
+		defence_score	FLOAT(24),
+		UNIQUE (team_id, service_id, round)
+	);`)
+
+	return
+}
+
+// AddServiceResult add result of team in service for round to database,
+// unlike round result it's not cumulative
+func AddServiceResult(db *sql.DB, res ServiceResult) (id int, err error) {
+
+	stmt, err := db.Prepare("INSERT INTO service_result " +
+		"(team_id, service_id, round, attack_score, defence_score) " +
+		"VALUES ($1, $2, $3, $4, $5) RETURNING id")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRow(res.TeamID, res.ServiceID, res.Round,
+		res.AttackScore, res.DefenceScore).Scan(&id)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetServiceResults get results of team for each service summed over all
+// rounds, round field contains last counted round
+func GetServiceResults(db *sql.DB, teamID int) (results []ServiceResult,
+	err error) {
+
+	stmt, err := db.Prepare("SELECT service_id, MAX(round), " +
+		"SUM(attack_score), SUM(defence_score) FROM service_result " +
+		"WHERE team_id=$1 GROUP BY service_id ORDER BY service_id")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query(teamID)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		res := ServiceResult{TeamID: teamID}
+
+		err = rows.Scan(&res.ServiceID, &res.Round, &res.AttackScore,
+			&res.DefenceScore)
+		if err != nil {
+			return
+		}
+
+		results = append(results, res)
+	}
+
+	return
+}
@@ -0,0 +1,64 @@
+/**
+ * @file service_result_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test work with service result table
+ */
+
+package steward_test
+
+import (
+	"log"
+	"testing"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestGetServiceResults(t *testing.T) {
+
+	db, err := openDB()
+	if err != nil {
+		log.Fatalln("Open database failed:", err)
+	}
+
+	defer db.Close()
+
+	for round := 1; round <= 2; round++ {
+		for svc := 1; svc <= 3; svc++ {
+			res := steward.ServiceResult{TeamID: 10, ServiceID: svc,
+				Round: round, AttackScore: float64(svc),
+				DefenceScore: 0.5}
+
+			_, err = steward.AddServiceResult(db.db, res)
+			if err != nil {
+				log.Fatalln("Add service result failed:", err)
+			}
+		}
+	}
+
+	// Result for round already exist
+	_, err = steward.AddServiceResult(db.db, steward.ServiceResult{
+		TeamID: 10, ServiceID: 1, Round: 1})
+	if err == nil {
+		log.Fatalln("Add duplicate service result not failed")
+	}
+
+	results, err := steward.GetServiceResults(db.db, 10)
+	if err != nil {
+		log.Fatalln("Get service results failed:", err)
+	}
+
+	if len(results) != 3 {
+		log.Fatalln("Get", len(results), "service results instead 3")
+	}
+
+	for i, res := range results {
+		svc := i + 1
+		if res.ServiceID != svc || res.Round != 2 ||
+			res.AttackScore != float64(svc*2) ||
+			res.DefenceScore != 1 {
+			log.Fatalln("Invalid service result:", res)
+		}
+	}
+}
@@ -37,7 +37,9 @@ func TestGetServices(t *testing.T) {
 	defer db.Close()
 
 	svc := steward.Service{ID: -1, Name: "lol", Port: 10,
-		CheckerPath: "/test", UDP: false}
+		CheckerPath: "/test", UDP: false, Weight: 1.5,
+		JSONProtocol: true, Checker: "builtin", Workers: 4, Vulns: 2,
+		Probe: "banner", ProbeSend: "HELP\n", ProbeExpect: "^OK"}
 
 	const services_amount int = 5
 
@@ -8,7 +8,11 @@
 
 package steward
 
-import "database/sql"
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
 
 // ServiceState provide type for service status
 type ServiceState int
@@ -55,6 +59,11 @@ type Status struct {
 	TeamID    int
 	ServiceID int
 	State     ServiceState
+	Message   string // public message of checker for team
+	Logs      string // output of checker runs, for jury only
+	ExitCode  int    // of last checker run
+	Duration  time.Duration
+	Timestamp time.Time
 }
 
 func createStatusTable(db *sql.DB) (err error) {
@@ -66,6 +75,10 @@ func createStatusTable(db *sql.DB) (err error) {
 		team_id	INTEGER NOT NULL,
 		service_id	INTEGER NOT NULL,
 		state	INTEGER NOT NULL,
+		message	TEXT NOT NULL DEFAULT '',
+		logs	TEXT NOT NULL DEFAULT '',
+		exit_code	INTEGER NOT NULL DEFAULT 0,
+		duration	BIGINT NOT NULL DEFAULT 0,
 		timestamp	TIMESTAMP with time zone DEFAULT now()
 	)`)
 
@@ -76,15 +89,18 @@ func createStatusTable(db *sql.DB) (err error) {
 func PutStatus(db *sql.DB, status Status) (err error) {
 
 	stmt, err := db.Prepare("INSERT INTO status (round, team_id, " +
-		"service_id, state) VALUES ($1, $2, $3, $4)")
+		"service_id, state, message, logs, exit_code, duration) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8)")
 	if err != nil {
 		return
 	}
 
 	defer stmt.Close()
 
+	// Output of checker can be invalid text
 	_, err = stmt.Exec(status.Round, status.TeamID, status.ServiceID,
-		status.State)
+		status.State, ValidText(status.Message), ValidText(status.Logs),
+		status.ExitCode, int64(status.Duration))
 	if err != nil {
 		return
 	}
@@ -149,3 +165,113 @@ func GetState(db *sql.DB, halfStatus Status) (state ServiceState, err error) {
 
 	return
 }
+
+// GetMessage get public message of last service status
+func GetMessage(db *sql.DB, halfStatus Status) (message string, err error) {
+
+	stmt, err := db.Prepare(
+		"SELECT message FROM status WHERE round=$1 AND team_id=$2 " +
+			"AND service_id=$3 " +
+			"AND ID = (SELECT MAX(ID) FROM status " +
+			"WHERE round=$1 AND team_id=$2 AND service_id=$3)")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	err = stmt.QueryRow(halfStatus.Round, halfStatus.TeamID,
+		halfStatus.ServiceID).Scan(&message)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetStateCounts get count of each state of team service from first round
+// to given round inclusive
+func GetStateCounts(db *sql.DB, round, teamID, serviceID int) (
+	counts map[ServiceState]int, err error) {
+
+	counts = make(map[ServiceState]int)
+
+	stmt, err := db.Prepare("SELECT state, COUNT(*) FROM status " +
+		"WHERE round<=$1 AND team_id=$2 AND service_id=$3 " +
+		"GROUP BY state")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	rows, err := stmt.Query(round, teamID, serviceID)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var state, count int
+
+		err = rows.Scan(&state, &count)
+		if err != nil {
+			return
+		}
+
+		counts[ServiceState(state)] = count
+	}
+
+	return
+}
+
+// GetStatuses get full statuses with checker logs, zero round, team id or
+// service id means any
+func GetStatuses(db *sql.DB, round, teamID, serviceID int) (
+	statuses []Status, err error) {
+
+	query := "SELECT round, team_id, service_id, state, message, logs, " +
+		"exit_code, duration, timestamp FROM status WHERE TRUE"
+
+	var args []interface{}
+
+	for _, filter := range []struct {
+		column string
+		value  int
+	}{{"round", round}, {"team_id", teamID}, {"service_id", serviceID}} {
+		if filter.value != 0 {
+			args = append(args, filter.value)
+			query += fmt.Sprintf(" AND %s=$%d", filter.column,
+				len(args))
+		}
+	}
+
+	rows, err := db.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var status Status
+		var state int
+		var duration int64
+
+		err = rows.Scan(&status.Round, &status.TeamID,
+			&status.ServiceID, &state, &status.Message,
+			&status.Logs, &status.ExitCode, &duration,
+			&status.Timestamp)
+		if err != nil {
+			return
+		}
+
+		status.State = ServiceState(state)
+		status.Duration = time.Duration(duration)
+
+		statuses = append(statuses, status)
+	}
+
+	return
+}
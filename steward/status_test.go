@@ -11,6 +11,7 @@ package steward_test
 import (
 	"log"
 	"testing"
+	"time"
 )
 
 import "github.com/jollheef/tin_foil_hat/steward"
@@ -98,3 +99,104 @@ func TestGetServiceCurrentStatus(t *testing.T) {
 	}
 
 }
+
+func TestGetMessage(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	status := steward.Status{Round: 1, TeamID: 2, ServiceID: 3,
+		State: steward.StatusMumble, Message: "first"}
+
+	steward.PutStatus(db.db, status)
+
+	status.Message = "Cannot login"
+
+	steward.PutStatus(db.db, status)
+
+	message, err := steward.GetMessage(db.db, status)
+	if err != nil {
+		log.Fatalln("Get message failed:", err)
+	}
+
+	if message != status.Message {
+		log.Fatalln("Get message", message, "instead", status.Message)
+	}
+}
+
+func TestGetStateCounts(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	team := 2
+	service := 3
+
+	for round := 1; round <= 3; round++ {
+		steward.PutStatus(db.db, steward.Status{Round: round,
+			TeamID: team, ServiceID: service,
+			State: steward.StatusUP})
+		steward.PutStatus(db.db, steward.Status{Round: round,
+			TeamID: team, ServiceID: service,
+			State: steward.StatusMumble})
+	}
+
+	// Other service
+	steward.PutStatus(db.db, steward.Status{Round: 1, TeamID: team,
+		ServiceID: service + 1, State: steward.StatusDown})
+
+	counts, err := steward.GetStateCounts(db.db, 2, team, service)
+	if err != nil {
+		log.Fatalln("Get state counts failed:", err)
+	}
+
+	if len(counts) != 2 || counts[steward.StatusUP] != 2 ||
+		counts[steward.StatusMumble] != 2 {
+		log.Fatalln("Invalid state counts:", counts)
+	}
+}
+
+func TestGetStatuses(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	for round := 1; round <= 2; round++ {
+		for team := 1; team <= 2; team++ {
+			steward.PutStatus(db.db, steward.Status{Round: round,
+				TeamID: team, ServiceID: 1,
+				State: steward.StatusMumble, Logs: "Traceback",
+				ExitCode: 2, Duration: time.Second})
+		}
+	}
+
+	statuses, err := steward.GetStatuses(db.db, 0, 0, 0)
+	if err != nil {
+		log.Fatalln("Get statuses failed:", err)
+	}
+
+	if len(statuses) != 4 {
+		log.Fatalln("Get", len(statuses), "statuses instead 4")
+	}
+
+	statuses, err = steward.GetStatuses(db.db, 2, 1, 1)
+	if err != nil {
+		log.Fatalln("Get statuses failed:", err)
+	}
+
+	if len(statuses) != 1 {
+		log.Fatalln("Get", len(statuses), "statuses instead 1")
+	}
+
+	status := statuses[0]
+
+	if status.Round != 2 || status.TeamID != 1 ||
+		status.State != steward.StatusMumble ||
+		status.Logs != "Traceback" || status.ExitCode != 2 ||
+		status.Duration != time.Second {
+		log.Fatalln("Get invalid status:", status)
+	}
+}
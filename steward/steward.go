@@ -59,6 +59,26 @@ func createSchema(db *sql.DB) error {
 		return err
 	}
 
+	err = createServiceResultTable(db)
+	if err != nil {
+		return err
+	}
+
+	err = createFlagKeyTable(db)
+	if err != nil {
+		return err
+	}
+
+	err = createSubmissionTable(db)
+	if err != nil {
+		return err
+	}
+
+	err = createConnectionTable(db)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -82,7 +102,8 @@ func OpenDatabase(path string) (db *sql.DB, err error) {
 func CleanDatabase(db *sql.DB) (err error) {
 
 	tables := []string{"team", "advisory", "captured_flag", "flag",
-		"service", "status", "round", "round_result"}
+		"service", "status", "round", "round_result", "service_result",
+		"flag_key", "submission", "connection"}
 
 	for _, table := range tables {
 
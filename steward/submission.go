@@ -0,0 +1,150 @@
+/**
+ * @file submission.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief queries for submission table
+ *
+ * Every flag submission attempt is kept with verdict, it is used for
+ * resolve disputes with teams.
+ */
+
+package steward
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Submission contains flag submission attempt
+type Submission struct {
+	ID        int
+	TeamID    int // zero if team not found
+	Addr      string
+	Flag      string
+	Verdict   string
+	Accepted  bool
+	Timestamp time.Time
+}
+
+// SubmissionStat contains count of submissions of team with same verdict
+type SubmissionStat struct {
+	TeamID   int
+	Verdict  string
+	Accepted bool
+	Count    int
+	First    time.Time
+	Last     time.Time
+}
+
+func createSubmissionTable(db *sql.DB) (err error) {
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS "submission" (
+		id	SERIAL PRIMARY KEY,
+		team_id	INTEGER NOT NULL,
+		addr	TEXT NOT NULL,
+		flag	TEXT NOT NULL,
+		verdict	TEXT NOT NULL,
+		accepted	BOOLEAN NOT NULL,
+		timestamp	TIMESTAMP with time zone DEFAULT now()
+	)`)
+
+	return
+}
+
+// AddSubmission add submission attempt to database
+func AddSubmission(db *sql.DB, s Submission) (err error) {
+
+	stmt, err := db.Prepare("INSERT INTO submission (team_id, addr, " +
+		"flag, verdict, accepted) VALUES ($1, $2, $3, $4, $5)")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.Exec(s.TeamID, s.Addr, s.Flag, s.Verdict, s.Accepted)
+
+	return
+}
+
+// GetSubmissions returns submissions of team
+func GetSubmissions(db *sql.DB, teamID int) (subs []Submission, err error) {
+
+	rows, err := db.Query("SELECT id, team_id, addr, flag, verdict, "+
+		"accepted, timestamp FROM submission WHERE team_id=$1 "+
+		"ORDER BY id", teamID)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var s Submission
+
+		err = rows.Scan(&s.ID, &s.TeamID, &s.Addr, &s.Flag, &s.Verdict,
+			&s.Accepted, &s.Timestamp)
+		if err != nil {
+			return
+		}
+
+		subs = append(subs, s)
+	}
+
+	return
+}
+
+// GetAllSubmissions returns submissions of all teams
+func GetAllSubmissions(db *sql.DB) (subs []Submission, err error) {
+
+	rows, err := db.Query("SELECT id, team_id, addr, flag, verdict, " +
+		"accepted, timestamp FROM submission ORDER BY id")
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var s Submission
+
+		err = rows.Scan(&s.ID, &s.TeamID, &s.Addr, &s.Flag, &s.Verdict,
+			&s.Accepted, &s.Timestamp)
+		if err != nil {
+			return
+		}
+
+		subs = append(subs, s)
+	}
+
+	return
+}
+
+// GetSubmissionStats returns count of submissions by team and verdict
+func GetSubmissionStats(db *sql.DB) (stats []SubmissionStat, err error) {
+
+	rows, err := db.Query("SELECT team_id, verdict, accepted, COUNT(*), " +
+		"MIN(timestamp), MAX(timestamp) FROM submission " +
+		"GROUP BY team_id, verdict, accepted ORDER BY team_id")
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var stat SubmissionStat
+
+		err = rows.Scan(&stat.TeamID, &stat.Verdict, &stat.Accepted,
+			&stat.Count, &stat.First, &stat.Last)
+		if err != nil {
+			return
+		}
+
+		stats = append(stats, stat)
+	}
+
+	return
+}
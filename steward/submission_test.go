@@ -0,0 +1,65 @@
+/**
+ * @file submission_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test work with submission table
+ */
+
+package steward_test
+
+import (
+	"log"
+	"testing"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestSubmissions(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	for _, s := range []steward.Submission{
+		{TeamID: 1, Addr: "10.0.1.2", Flag: "a", Verdict: "Captured!",
+			Accepted: true},
+		{TeamID: 1, Addr: "10.0.1.2", Flag: "b", Verdict: "Invalid flag"},
+		{TeamID: 1, Addr: "10.0.1.3", Flag: "c", Verdict: "Invalid flag"},
+		{TeamID: 2, Addr: "10.0.2.2", Flag: "d", Verdict: "Flag expired"},
+	} {
+		err = steward.AddSubmission(db.db, s)
+		if err != nil {
+			log.Fatalln("Add submission failed:", err)
+		}
+	}
+
+	subs, err := steward.GetSubmissions(db.db, 1)
+	if err != nil {
+		log.Fatalln("Get submissions failed:", err)
+	}
+
+	if len(subs) != 3 || subs[2].Addr != "10.0.1.3" || !subs[0].Accepted {
+		log.Fatalln("Invalid submissions:", subs)
+	}
+
+	subs, err = steward.GetAllSubmissions(db.db)
+	if err != nil || len(subs) != 4 {
+		log.Fatalln("Get all submissions failed:", subs, err)
+	}
+
+	stats, err := steward.GetSubmissionStats(db.db)
+	if err != nil {
+		log.Fatalln("Get submission stats failed:", err)
+	}
+
+	count := map[int]map[string]int{1: {}, 2: {}}
+	for _, stat := range stats {
+		count[stat.TeamID][stat.Verdict] = stat.Count
+	}
+
+	if len(stats) != 3 || count[1]["Invalid flag"] != 2 ||
+		count[1]["Captured!"] != 1 || count[2]["Flag expired"] != 1 {
+		log.Fatalln("Invalid submission stats:", stats)
+	}
+}
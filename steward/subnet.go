@@ -0,0 +1,123 @@
+/**
+ * @file subnet.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief identify team by address
+ *
+ * Team subnets (comma separated list of CIDR, IPv4 or IPv6) are kept in
+ * binary prefix tree, address matches team with longest prefix, so team
+ * subnet inside of jury subnet belongs to team.
+ */
+
+package steward
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+// ParseSubnets parse comma separated list of subnets in CIDR notation
+func ParseSubnets(subnets string) (nets []*net.IPNet, err error) {
+
+	for _, s := range strings.Split(subnets, ",") {
+
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+
+		nets = append(nets, ipnet)
+	}
+
+	if len(nets) == 0 {
+		err = errors.New("no subnets in '" + subnets + "'")
+	}
+
+	return
+}
+
+type subnetNode struct {
+	child [2]*subnetNode
+	team  *Team
+}
+
+// SubnetTree is binary trie of subnet prefixes, IPv4 subnets are stored as
+// IPv4-mapped IPv6 so both families share one tree
+type SubnetTree struct {
+	root subnetNode
+}
+
+func ipBit(ip net.IP, i int) int {
+	return int(ip[i/8]>>uint(7-i%8)) & 1
+}
+
+func (t *SubnetTree) insert(ipnet *net.IPNet, team *Team) {
+
+	ones, bits := ipnet.Mask.Size()
+	if bits == 8*net.IPv4len {
+		ones += 8 * (net.IPv6len - net.IPv4len) // mapped prefix
+	}
+
+	ip := ipnet.IP.To16()
+
+	node := &t.root
+	for i := 0; i < ones; i++ {
+		b := ipBit(ip, i)
+		if node.child[b] == nil {
+			node.child[b] = &subnetNode{}
+		}
+		node = node.child[b]
+	}
+
+	node.team = team
+}
+
+// Lookup returns team with longest prefix which contains address, nil if
+// address is not in subnets of teams
+func (t *SubnetTree) Lookup(ip net.IP) (team *Team) {
+
+	ip = ip.To16()
+	if ip == nil {
+		return
+	}
+
+	node := &t.root
+	for i := 0; node != nil; i++ {
+		if node.team != nil {
+			team = node.team
+		}
+		if i == 8*net.IPv6len {
+			break
+		}
+		node = node.child[ipBit(ip, i)]
+	}
+
+	return
+}
+
+// NewSubnetTree returns tree of subnets of teams
+func NewSubnetTree(teams []Team) (t *SubnetTree, err error) {
+
+	t = &SubnetTree{}
+
+	for i := range teams {
+
+		nets, err := ParseSubnets(teams[i].Subnet)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ipnet := range nets {
+			t.insert(ipnet, &teams[i])
+		}
+	}
+
+	return
+}
@@ -0,0 +1,61 @@
+/**
+ * @file subnet_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test identify team by address
+ */
+
+package steward_test
+
+import (
+	"log"
+	"net"
+	"testing"
+
+	"github.com/jollheef/tin_foil_hat/steward"
+)
+
+func TestSubnetTree(*testing.T) {
+
+	teams := []steward.Team{
+		{ID: 1, Name: "Foo", Subnet: "10.0.1.0/24"},
+		{ID: 2, Name: "Bar", Subnet: "10.0.2.0/24, 10.60.2.0/24"},
+		{ID: 3, Name: "Baz", Subnet: "fd00:0:0:3::/64"},
+		{ID: 4, Name: "Qux", Subnet: "10.0.0.0/16"},
+		{ID: 5, Name: "Quux", Subnet: "10.0.3.0/24"},
+	}
+
+	tree, err := steward.NewSubnetTree(teams)
+	if err != nil {
+		log.Fatalln("Build tree failed:", err)
+	}
+
+	for addr, id := range map[string]int{
+		"10.0.1.15":             1, // inside of 10.0.0.0/16
+		"10.0.2.1":              2,
+		"10.60.2.254":           2,
+		"::ffff:10.0.2.7":       2, // IPv4-mapped
+		"fd00:0:0:3::42":        3,
+		"fd00:0:0:3:1:2:3:4":    3,
+		"10.0.3.1":              5, // inside of 10.0.0.0/16
+		"10.0.4.1":              4,
+		"10.1.1.1":              0,
+		"fd00:0:0:4::1":         0,
+		"2001:db8::1":           0,
+		"127.0.0.1":             0,
+		"fd00:0:0:3:ffff::ffff": 3,
+	} {
+		team := tree.Lookup(net.ParseIP(addr))
+		if (team == nil && id != 0) || (team != nil && team.ID != id) {
+			log.Fatalf("Invalid team for %s: %v instead %d",
+				addr, team, id)
+		}
+	}
+
+	_, err = steward.NewSubnetTree([]steward.Team{{Name: "Invalid",
+		Subnet: "10.0.1/24"}})
+	if err == nil {
+		log.Fatalln("Invalid subnet accepted")
+	}
+}
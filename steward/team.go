@@ -8,7 +8,10 @@
 
 package steward
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+)
 
 // Team contains info about team
 type Team struct {
@@ -18,6 +21,7 @@ type Team struct {
 	Vulnbox   string
 	UseNetbox bool
 	Netbox    string
+	Token     string // used for submit flags over http
 }
 
 func createTeamTable(db *sql.DB) (err error) {
@@ -29,8 +33,17 @@ func createTeamTable(db *sql.DB) (err error) {
 		subnet		TEXT NOT NULL UNIQUE,
 		vulnbox		TEXT NOT NULL UNIQUE,
 		use_netbox	BOOLEAN NOT NULL,
-                netbox		TEXT NOT NULL
+                netbox		TEXT NOT NULL,
+		token		TEXT NOT NULL DEFAULT ''
 	)`)
+	if err != nil {
+		return
+	}
+
+	// Token identifies team, but teams without token are allowed
+	_, err = db.Exec(`
+	CREATE UNIQUE INDEX IF NOT EXISTS team_token_unique ON team (token)
+		WHERE token <> ''`)
 	return
 }
 
@@ -38,8 +51,8 @@ func createTeamTable(db *sql.DB) (err error) {
 func AddTeam(db *sql.DB, team Team) (id int, err error) {
 
 	stmt, err := db.Prepare("INSERT INTO team (name, subnet, vulnbox, " +
-		"use_netbox, netbox) " +
-		"VALUES ($1, $2, $3, $4, $5) RETURNING id")
+		"use_netbox, netbox, token) " +
+		"VALUES ($1, $2, $3, $4, $5, $6) RETURNING id")
 	if err != nil {
 		return
 	}
@@ -47,7 +60,7 @@ func AddTeam(db *sql.DB, team Team) (id int, err error) {
 	defer stmt.Close()
 
 	err = stmt.QueryRow(team.Name, team.Subnet, team.Vulnbox,
-		team.UseNetbox, team.Netbox).Scan(&id)
+		team.UseNetbox, team.Netbox, team.Token).Scan(&id)
 	if err != nil {
 		return
 	}
@@ -59,7 +72,8 @@ func AddTeam(db *sql.DB, team Team) (id int, err error) {
 func GetTeams(db *sql.DB) (teams []Team, err error) {
 
 	rows, err := db.Query(
-		"SELECT id, name, subnet, vulnbox, use_netbox, netbox FROM team")
+		"SELECT id, name, subnet, vulnbox, use_netbox, netbox, token " +
+			"FROM team")
 	if err != nil {
 		return
 	}
@@ -70,7 +84,7 @@ func GetTeams(db *sql.DB) (teams []Team, err error) {
 		var team Team
 
 		err = rows.Scan(&team.ID, &team.Name, &team.Subnet,
-			&team.Vulnbox, &team.UseNetbox, &team.Netbox)
+			&team.Vulnbox, &team.UseNetbox, &team.Netbox, &team.Token)
 		if err != nil {
 			return
 		}
@@ -85,8 +99,8 @@ func GetTeams(db *sql.DB) (teams []Team, err error) {
 func GetTeam(db *sql.DB, teamID int) (team Team, err error) {
 
 	stmt, err := db.Prepare(
-		"SELECT name, subnet, vulnbox, use_netbox, netbox FROM team " +
-			"WHERE id=$1")
+		"SELECT name, subnet, vulnbox, use_netbox, netbox, token " +
+			"FROM team WHERE id=$1")
 	if err != nil {
 		return
 	}
@@ -96,6 +110,34 @@ func GetTeam(db *sql.DB, teamID int) (team Team, err error) {
 	team.ID = teamID
 
 	err = stmt.QueryRow(teamID).Scan(&team.Name, &team.Subnet,
+		&team.Vulnbox, &team.UseNetbox, &team.Netbox, &team.Token)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// GetTeamByToken get team by http submission token from database
+func GetTeamByToken(db *sql.DB, token string) (team Team, err error) {
+
+	if token == "" {
+		err = errors.New("empty token")
+		return
+	}
+
+	stmt, err := db.Prepare(
+		"SELECT id, name, subnet, vulnbox, use_netbox, netbox FROM team " +
+			"WHERE token=$1")
+	if err != nil {
+		return
+	}
+
+	defer stmt.Close()
+
+	team.Token = token
+
+	err = stmt.QueryRow(token).Scan(&team.ID, &team.Name, &team.Subnet,
 		&team.Vulnbox, &team.UseNetbox, &team.Netbox)
 	if err != nil {
 		return
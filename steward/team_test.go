@@ -87,3 +87,79 @@ func TestGetTeam(t *testing.T) {
 		log.Fatalln("Get invalid team broken")
 	}
 }
+
+func TestGetTeamByToken(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	team1 := steward.Team{
+		ID: -1, Name: "MySuperTeam", Subnet: "192.168.111/24",
+		Vulnbox: "pl.hold1", UseNetbox: false, Netbox: "nb.hold1",
+		Token: "secret1"}
+	team2 := steward.Team{
+		ID: -1, Name: "MyFooTeam", Subnet: "192.168.112/24",
+		Vulnbox: "pl.hold2", UseNetbox: true, Netbox: "nb.hold2"}
+
+	team1.ID, _ = steward.AddTeam(db.db, team1)
+	team2.ID, _ = steward.AddTeam(db.db, team2)
+
+	_team1, err := steward.GetTeamByToken(db.db, team1.Token)
+	if err != nil {
+		log.Fatalln("Get team by token failed:", err)
+	}
+
+	if _team1 != team1 {
+		log.Fatalln("Added team broken")
+	}
+
+	// Team without token cannot be found by empty token
+	_, err = steward.GetTeamByToken(db.db, "")
+	if err == nil {
+		log.Fatalln("Get team by empty token broken")
+	}
+
+	_, err = steward.GetTeamByToken(db.db, "invalid")
+	if err == nil {
+		log.Fatalln("Get team by invalid token broken")
+	}
+}
+
+func TestTeamTokenUnique(t *testing.T) {
+
+	db, err := openDB()
+
+	defer db.Close()
+
+	team1 := steward.Team{
+		ID: -1, Name: "MySuperTeam", Subnet: "192.168.111/24",
+		Vulnbox: "pl.hold1", Netbox: "nb.hold1", Token: "secret"}
+	team2 := steward.Team{
+		ID: -1, Name: "MyFooTeam", Subnet: "192.168.112/24",
+		Vulnbox: "pl.hold2", Netbox: "nb.hold2", Token: "secret"}
+
+	_, err = steward.AddTeam(db.db, team1)
+	if err != nil {
+		log.Fatalln("Add team failed:", err)
+	}
+
+	_, err = steward.AddTeam(db.db, team2)
+	if err == nil {
+		log.Fatalln("Team with same token added")
+	}
+
+	// Many teams can be without token
+	for name, subnet := range map[string]string{
+		"MyBarTeam": "192.168.113/24",
+		"MyBazTeam": "192.168.114/24"} {
+
+		team := steward.Team{ID: -1, Name: name, Subnet: subnet,
+			Vulnbox: name, Netbox: name}
+
+		_, err = steward.AddTeam(db.db, team)
+		if err != nil {
+			log.Fatalln("Add team without token failed:", err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+/**
+ * @file text.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief prepare text for database
+ *
+ * Text from checkers and teams can contain anything, but postgres TEXT
+ * does not accept NUL bytes and invalid UTF-8, so insert would fail.
+ */
+
+package steward
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidText replace invalid UTF-8 and remove NUL bytes
+func ValidText(s string) string {
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	return strings.Replace(s, "\x00", "", -1)
+}
+
+// TruncateText cut valid text to at most n bytes on rune boundary
+func TruncateText(s string, n int) string {
+
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
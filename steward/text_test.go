@@ -0,0 +1,35 @@
+/**
+ * @file text_test.go
+ * @author Mikhail Klementyev jollheef<AT>riseup.net
+ * @license GNU AGPLv3
+ * @date October, 2026
+ * @brief test preparing text for database
+ */
+
+package steward_test
+
+import (
+	"log"
+	"testing"
+	"unicode/utf8"
+)
+
+import "github.com/jollheef/tin_foil_hat/steward"
+
+func TestValidText(*testing.T) {
+
+	s := steward.ValidText("a\x00b\xffc")
+	if s != "ab�c" || !utf8.ValidString(s) {
+		log.Fatalf("Invalid text %q", s)
+	}
+
+	// Cut inside of two-byte rune
+	if steward.TruncateText("aбв", 2) != "a" {
+		log.Fatalln("Rune is cut")
+	}
+
+	if steward.TruncateText("aбв", 3) != "aб" ||
+		steward.TruncateText("aбв", 10) != "aбв" {
+		log.Fatalln("Invalid truncate")
+	}
+}
@@ -5,71 +5,297 @@
  * @date September, 2015
  * @brief work with flags
  *
- * Contain functions for work with flags, such as generate rsa key for validate
+ * Contain functions for work with flags, such as generate key for validate
  * flag, generate flag and validate flag.
+ *
+ * Flag is signed by HMAC-SHA256. Payload of flag contains round, team id,
+ * service id and random nonce, so metadata of valid flag is known without
+ * database. Payload and truncated signature are encoded by alphabet of flag
+ * format (see SetFormat).
  */
 
 package vexillary
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
-	"crypto/rsa"
-	"encoding/hex"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	metaSize  = 6  // round (3 bytes), team id (2 bytes), service id (1 byte)
+	nonceSize = 4  // random part
+	minMAC    = 6  // min size of truncated signature
+	keySize   = 32 // of HMAC key
+
+	maxRound   = 1<<24 - 1
+	maxTeam    = 1<<16 - 1
+	maxService = 1<<8 - 1
+)
+
+// Key is secret key for sign flags
+type Key []byte
+
+// Info contains metadata of flag
+type Info struct {
+	Round     int
+	TeamID    int
+	ServiceID int
+}
+
+// Format of flag: literal prefix, body of characters from alphabet and
+// literal suffix
+type Format struct {
+	Pattern  string
+	prefix   string
+	suffix   string
+	alphabet string
+	length   int
+	size     int // bytes of payload and signature encoded in body
+	re       *regexp.Regexp
+}
+
+// DefaultFormat is 40 hex characters with '=' at end
+const DefaultFormat = "[0-9a-f]{40}="
+
+var (
+	patternRe = regexp.MustCompile(`^(.*?)\[([^\]]+)\]\{(\d+)\}(.*)$`)
+	escapeRe  = regexp.MustCompile(`\\(.)`)
+	format, _ = NewFormat(DefaultFormat)
 )
 
-// GenerateKey generate rsa key
-func GenerateKey() (priv *rsa.PrivateKey, err error) {
-	// 128 is minimal valid length for RSA key, which can validate flag
-	return rsa.GenerateKey(rand.Reader, 128)
+func unescape(s string) string {
+	return escapeRe.ReplaceAllString(s, "$1")
 }
 
-// GenerateFlag generate signed flag
-func GenerateFlag(priv *rsa.PrivateKey) (string, error) {
+// alphabet returns characters of regexp class, e.g. A-Z0-9
+func alphabet(class string) (chars string, err error) {
+
+	seen := make(map[rune]bool)
+
+	runes := []rune(unescape(class))
+
+	for i := 0; i < len(runes); i++ {
+		from, to := runes[i], runes[i]
+		if i+2 < len(runes) && runes[i+1] == '-' {
+			to = runes[i+2]
+			i += 2
+		}
 
-	randBuf := make([]byte, 4)
+		if from > to || to > 0x7f {
+			return "", fmt.Errorf("invalid class '%s'", class)
+		}
 
-	_, err := rand.Read(randBuf)
+		for r := from; r <= to; r++ {
+			if !seen[r] {
+				seen[r] = true
+				chars += string(r)
+			}
+		}
+	}
+
+	if len(chars) < 2 {
+		err = fmt.Errorf("class '%s' is too small", class)
+	}
+
+	return
+}
 
+// NewFormat parse format like [A-Z0-9]{31}= or CTF\{[a-f0-9]{32}\}
+func NewFormat(pattern string) (f Format, err error) {
+
+	m := patternRe.FindStringSubmatch(pattern)
+	if m == nil {
+		err = errors.New("format must be PREFIX[CLASS]{LENGTH}SUFFIX")
+		return
+	}
+
+	f.Pattern = pattern
+	f.prefix, f.suffix = unescape(m[1]), unescape(m[4])
+
+	f.alphabet, err = alphabet(m[2])
 	if err != nil {
-		return "", err
+		return
 	}
 
-	signature, err := rsa.SignPKCS1v15(rand.Reader, priv, 0, randBuf)
+	f.length, err = strconv.Atoi(m[3])
+	if err != nil {
+		return
+	}
+
+	bits := float64(f.length) * math.Log2(float64(len(f.alphabet)))
+	f.size = int(bits / 8)
+
+	if f.size < metaSize+nonceSize+minMAC {
+		err = fmt.Errorf("format '%s' is too short, need %d bits",
+			pattern, (metaSize+nonceSize+minMAC)*8)
+		return
+	}
 
+	class := strings.Replace(regexp.QuoteMeta(f.alphabet), "-", `\-`, -1)
+
+	f.re, err = regexp.Compile(fmt.Sprintf("^%s[%s]{%d}%s$",
+		regexp.QuoteMeta(f.prefix), class, f.length,
+		regexp.QuoteMeta(f.suffix)))
+
+	return
+}
+
+// SetFormat set format of generated and validated flags
+func SetFormat(pattern string) (err error) {
+
+	f, err := NewFormat(pattern)
 	if err != nil {
-		return "", err
+		return
+	}
+
+	format = f
+	return
+}
+
+func (f Format) encode(buf []byte) string {
+
+	n := new(big.Int).SetBytes(buf)
+	base := big.NewInt(int64(len(f.alphabet)))
+	mod := new(big.Int)
+
+	body := make([]byte, f.length)
+
+	for i := f.length - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		body[i] = f.alphabet[mod.Int64()]
 	}
 
-	return fmt.Sprintf("%x%x=", randBuf, signature), nil
+	return f.prefix + string(body) + f.suffix
 }
 
-// ValidFlag verify flag
-func ValidFlag(flag string, pub rsa.PublicKey) (bool, error) {
+func (f Format) decode(flag string) (buf []byte, err error) {
+
+	if !f.re.MatchString(flag) {
+		err = errors.New("flag does not match format " + f.Pattern)
+		return
+	}
+
+	body := strings.TrimSuffix(strings.TrimPrefix(flag, f.prefix),
+		f.suffix)
+
+	n := new(big.Int)
+	base := big.NewInt(int64(len(f.alphabet)))
+
+	for _, c := range []byte(body) {
+		n.Mul(n, base)
+		n.Add(n, big.NewInt(int64(strings.IndexByte(f.alphabet, c))))
+	}
 
-	if len(flag) != 41 {
-		return false, errors.New("flag length is not 41")
+	if n.BitLen() > f.size*8 {
+		err = errors.New("flag is out of range")
+		return
 	}
 
-	if flag[40] != '=' {
-		return false, errors.New("no '=' at end")
+	buf = n.FillBytes(make([]byte, f.size))
+	return
+}
+
+func sign(key Key, data []byte, size int) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	sum := h.Sum(nil)
+	if size > len(sum) {
+		size = len(sum)
 	}
+	return sum[:size]
+}
+
+// GenerateKey generate key for sign flags
+func GenerateKey() (key Key, err error) {
+
+	key = make(Key, keySize)
 
-	randBuf, err := hex.DecodeString(flag[0:8])
+	_, err = rand.Read(key)
+
+	return
+}
+
+// GenerateFlag generate signed flag with metadata
+func GenerateFlag(key Key, round, team, service int) (string, error) {
+
+	if round < 0 || round > maxRound || team < 0 || team > maxTeam ||
+		service < 0 || service > maxService {
+		return "", errors.New("flag metadata is out of range")
+	}
 
+	buf := make([]byte, format.size)
+
+	binary.BigEndian.PutUint32(buf[0:], uint32(round)<<8)
+	binary.BigEndian.PutUint16(buf[3:], uint16(team))
+	buf[5] = byte(service)
+
+	payload := buf[:metaSize+nonceSize]
+
+	_, err := rand.Read(payload[metaSize:])
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
-	signature, err := hex.DecodeString(flag[8:40])
+	copy(buf[len(payload):], sign(key, payload, format.size-len(payload)))
 
+	return format.encode(buf), nil
+}
+
+// ParseFlag verify flag by any of keys and returns its metadata
+func ParseFlag(flag string, keys ...Key) (info Info, err error) {
+
+	buf, err := format.decode(flag)
 	if err != nil {
-		return false, err
+		return
 	}
 
-	err = rsa.VerifyPKCS1v15(&pub, 0, randBuf, signature)
+	payload := buf[:metaSize+nonceSize]
+	signature := buf[len(payload):]
+
+	if len(signature) > sha256.Size {
+		// Unused part of long formats is always zero
+		for _, b := range signature[sha256.Size:] {
+			if b != 0 {
+				err = errors.New("invalid signature")
+				return
+			}
+		}
+		signature = signature[:sha256.Size]
+	}
+
+	valid := false
+	for _, key := range keys {
+		if hmac.Equal(signature, sign(key, payload, len(signature))) {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		err = errors.New("invalid signature")
+		return
+	}
+
+	info.Round = int(binary.BigEndian.Uint32(buf[0:]) >> 8)
+	info.TeamID = int(binary.BigEndian.Uint16(buf[3:]))
+	info.ServiceID = int(buf[5])
+
+	return
+}
+
+// ValidFlag verify flag by any of keys
+func ValidFlag(flag string, keys ...Key) (bool, error) {
 
+	_, err := ParseFlag(flag, keys...)
 	if err != nil {
 		return false, err
 	}
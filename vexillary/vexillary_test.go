@@ -10,6 +10,7 @@ package vexillary_test
 
 import (
 	"log"
+	"regexp"
 	"testing"
 )
 
@@ -25,29 +26,94 @@ func TestGenerateKey(t *testing.T) {
 
 func TestGenerateFlag(t *testing.T) {
 
-	priv, _ := vexillary.GenerateKey()
+	key, _ := vexillary.GenerateKey()
 
-	_, err := vexillary.GenerateFlag(priv)
+	flag, err := vexillary.GenerateFlag(key, 1, 2, 3)
 	if err != nil {
 		log.Fatalln("Generate flag error:", err)
 	}
+
+	if !regexp.MustCompile("^[0-9a-f]{40}=$").MatchString(flag) {
+		log.Fatalln("Flag does not match default format:", flag)
+	}
+
+	_, err = vexillary.GenerateFlag(key, 1, 1<<16, 3)
+	if err == nil {
+		log.Fatalln("Flag with too big team id generated")
+	}
 }
 
 func TestValidFlag(t *testing.T) {
 
-	priv, _ := vexillary.GenerateKey()
-	flag, _ := vexillary.GenerateFlag(priv)
+	key, _ := vexillary.GenerateKey()
+	flag, _ := vexillary.GenerateFlag(key, 1, 2, 3)
 
 	// Check validation of valid flag
-	valid, err := vexillary.ValidFlag(flag, priv.PublicKey)
+	valid, err := vexillary.ValidFlag(flag, key)
 	if !valid {
 		log.Fatalln("Valid flag is invalid:", err)
 	}
 
 	// Check validation of invalid flag
 	invalid_flag := "aaaaaaa6a0993562af00d027aff63e9502754018="
-	valid, err = vexillary.ValidFlag(invalid_flag, priv.PublicKey)
+	valid, err = vexillary.ValidFlag(invalid_flag, key)
 	if valid {
 		log.Fatalln("Invalid flag is valid:", err)
 	}
+
+	// Check validation of flag signed on other key
+	otherKey, _ := vexillary.GenerateKey()
+	valid, err = vexillary.ValidFlag(flag, otherKey)
+	if valid {
+		log.Fatalln("Flag signed on other key is valid:", err)
+	}
+
+	// Flag is valid if any of keys is valid
+	valid, err = vexillary.ValidFlag(flag, otherKey, key)
+	if !valid {
+		log.Fatalln("Flag signed on one of keys is invalid:", err)
+	}
+}
+
+func TestParseFlag(t *testing.T) {
+
+	key, _ := vexillary.GenerateKey()
+
+	for _, format := range []string{"[A-Z0-9]{31}=", `CTF\{[a-f0-9]{32}\}`,
+		"FLAG_[a-zA-Z0-9_-]{40}"} {
+
+		err := vexillary.SetFormat(format)
+		if err != nil {
+			log.Fatalln("Set format failed:", err)
+		}
+
+		flag, err := vexillary.GenerateFlag(key, 70000, 12, 3)
+		if err != nil {
+			log.Fatalln("Generate flag error:", err)
+		}
+
+		info, err := vexillary.ParseFlag(flag, key)
+		if err != nil {
+			log.Fatalln("Parse flag", flag, "failed:", err)
+		}
+
+		if info.Round != 70000 || info.TeamID != 12 ||
+			info.ServiceID != 3 {
+			log.Fatalln("Invalid flag info:", info)
+		}
+
+		// Change of metadata breaks signature
+		forged := []byte(flag)
+		forged[5] ^= 1
+		if _, err = vexillary.ParseFlag(string(forged), key); err == nil {
+			log.Fatalln("Forged flag is valid:", string(forged))
+		}
+	}
+
+	// Too short format can not keep signature
+	if vexillary.SetFormat("[0-9]{20}") == nil {
+		log.Fatalln("Too short format is accepted")
+	}
+
+	vexillary.SetFormat(vexillary.DefaultFormat)
 }